@@ -3,6 +3,8 @@ package main
 import (
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/oadultradeepfield/olympliance-server/internal/databases"
@@ -15,13 +17,43 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	if _, err := services.DefaultKeyRing(); err != nil {
+		log.Fatalf("Error loading JWT signing keys: %v", err)
+	}
+
+	if _, err := services.DefaultPasswordPolicy(); err != nil {
+		log.Fatalf("Error loading password policy: %v", err)
+	}
+
+	if err := services.ValidateMailerEnv(); err != nil {
+		log.Fatalf("Error configuring mail: %v", err)
+	}
+
 	db := databases.InitDB()
 
 	reputationCalculator := services.NewReputationCalculator(db)
 	reputationCalculator.CalculateReputationOnStartup()
 
+	suspensions := services.NewSuspensionManager(db)
+	if err := suspensions.LiftExpired(); err != nil {
+		log.Fatalf("Error lifting expired suspensions: %v", err)
+	}
+	go suspensions.LiftExpiredEvery(time.Minute)
+
 	r := gin.Default()
 
+	// ClientIP only trusts X-Forwarded-For from the proxies listed in
+	// TRUSTED_PROXIES, so clients cannot dodge rate limits by spoofing it.
+	var trustedProxies []string
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		for _, proxy := range strings.Split(proxies, ",") {
+			trustedProxies = append(trustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Error setting trusted proxies: %v", err)
+	}
+
 	routes.InitRoutes(r, db)
 
 	if err := r.Run(":" + os.Getenv("PORT")); err != nil {
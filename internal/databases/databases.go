@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -30,10 +31,84 @@ func InitDB() *gorm.DB {
 		&models.Comment{},
 		&models.Interaction{},
 		&models.Category{},
+		&models.Session{},
+		&models.Identity{},
+		&models.APIToken{},
+		&models.TwoFactor{},
+		&models.RecoveryCode{},
+		&models.Setting{},
+		&models.EmailToken{},
+		&models.WebAuthnCredential{},
+		&models.Impersonation{},
+		&models.ImpersonationLog{},
+		&models.Role{},
+		&models.Permission{},
+		&models.RolePermission{},
+		&models.UserRole{},
+		&models.CategoryModerator{},
+		&models.Suspension{},
+		&models.ModerationLog{},
+		&models.Report{},
+		&models.AutomodRule{},
+		&models.AutomodMatch{},
 	); err != nil {
 		log.Fatalf("Error migrating database: %v", err)
 	}
 
+	// Google accounts used to be linked through users.google_id.
+	if db.Migrator().HasColumn("users", "google_id") {
+		if err := db.Exec(`
+			INSERT INTO identities (user_id, provider, subject, created_at)
+			SELECT user_id, 'google', google_id, created_at FROM users
+			WHERE google_id IS NOT NULL AND google_id <> ''
+			ON CONFLICT DO NOTHING
+		`).Error; err != nil {
+			log.Fatalf("Error migrating Google identities: %v", err)
+		}
+	}
+
+	if err := services.SeedRoles(db); err != nil {
+		log.Fatalf("Error seeding roles: %v", err)
+	}
+
+	// Moderators and admins used to be marked only by users.role_id.
+	if err := services.MigrateRoleIDs(db); err != nil {
+		log.Fatalf("Error migrating user roles: %v", err)
+	}
+
+	// Bans used to be recorded only by users.is_banned.
+	if err := db.Exec(`
+		INSERT INTO suspensions (user_id, reason, starts_at, created_at)
+		SELECT user_id, 'Banned before suspensions were recorded', NOW(), NOW() FROM users
+		WHERE is_banned AND NOT EXISTS (SELECT 1 FROM suspensions WHERE suspensions.user_id = users.user_id)
+	`).Error; err != nil {
+		log.Fatalf("Error migrating bans: %v", err)
+	}
+
+	// The moderation log is append-only, even for direct database access.
+	for _, statement := range []string{
+		`CREATE OR REPLACE FUNCTION moderation_logs_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'moderation_logs is append-only';
+		END;
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS moderation_logs_append_only ON moderation_logs`,
+		`CREATE TRIGGER moderation_logs_append_only BEFORE UPDATE OR DELETE ON moderation_logs
+		FOR EACH ROW EXECUTE FUNCTION moderation_logs_append_only()`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			log.Fatalf("Error protecting the moderation log: %v", err)
+		}
+	}
+
+	// Accounts created through Google before usernames were generated may hold
+	// display names that are not valid usernames.
+	if err := db.Model(&models.User{}).
+		Where("username !~ ?", `^[a-zA-Z0-9_-]+$`).
+		Update("needs_onboarding", true).Error; err != nil {
+		log.Fatalf("Error flagging users for onboarding: %v", err)
+	}
+
 	categories := []models.Category{
 		{Name: "General"},
 		{Name: "Mathematics"},
@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+)
+
+func (h *AuthHandler) GetCSRFToken(c *gin.Context) {
+	token, err := services.CSRFToken(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue CSRF token"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"csrf_token": token})
+}
@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+)
+
+// GetJWKS publishes the public keys tokens are signed with, so other services
+// can verify Olympliance tokens without sharing a secret.
+func (h *AuthHandler) GetJWKS(c *gin.Context) {
+	ring, err := services.DefaultKeyRing()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "JWT signing keys not configured"})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": ring.JWKS()})
+}
@@ -1,23 +1,22 @@
 package auth
 
 import (
+	"fmt"
+	"log"
 	"net/http"
-	"os"
-	"regexp"
-	"time"
+	"net/mail"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/oadultradeepfield/olympliance-server/internal/middleware"
 	"github.com/oadultradeepfield/olympliance-server/internal/models"
 	"github.com/oadultradeepfield/olympliance-server/internal/services"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func (h *AuthHandler) Register(c *gin.Context) {
 	var input struct {
 		Username string `json:"username" binding:"required"`
 		Password string `json:"password" binding:"required"`
+		Email    string `json:"email"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -25,18 +24,17 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	isValidUsername := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString
-	if !isValidUsername(input.Username) {
+	if !services.IsValidUsername(input.Username) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Username can only contain letters, numbers, underscores, and dashes"})
 		return
 	}
 
-	if len(input.Password) < 8 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters long"})
+	if err := services.CheckPassword(input.Password, input.Username, input.Email); err != nil {
+		services.RespondPasswordError(c, err)
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	hashedPassword, err := h.hasher.Hash(input.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
 		return
@@ -44,7 +42,22 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	user := models.User{
 		Username:     input.Username,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
+	}
+
+	if input.Email != "" {
+		email := services.NormalizeEmail(input.Email)
+		if _, err := mail.ParseAddress(email); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+			return
+		}
+
+		var existingUser models.User
+		if err := h.db.Where("email = ?", email).First(&existingUser).Error; err == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Email already in use"})
+			return
+		}
+		user.Email = &email
 	}
 
 	if err := h.db.Create(&user).Error; err != nil {
@@ -52,6 +65,12 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	if user.Email != nil {
+		if err := services.SendVerificationEmail(c.Request.Context(), h.mailer, h.emailTokens, &user, *user.Email); err != nil {
+			log.Printf("Error sending verification email to user %d: %v", user.UserID, err)
+		}
+	}
+
 	c.JSON(http.StatusCreated, gin.H{"message": "User created successfully"})
 }
 
@@ -66,59 +85,123 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if !h.checkThrottle(c, input.Username) {
+		return
+	}
+
 	var user models.User
 	if err := h.db.Where("username = ?", input.Username).First(&user).Error; err != nil {
+		h.verifyDummyPassword(input.Password)
+		h.recordFailure(input.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
-	if user.IsDeleted {
+	// Deleted and provider-only accounts fail exactly like a wrong password,
+	// so the response does not reveal which accounts exist or how they sign in.
+	if user.IsDeleted || user.PasswordHash == "" {
+		h.verifyDummyPassword(input.Password)
+		h.recordFailure(input.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
-	if user.GoogleID != "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account created with Google, login via Google only"})
+	valid, needsRehash, err := h.hasher.Verify(input.Password, user.PasswordHash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify password"})
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password)); err != nil {
+	if !valid {
+		h.recordFailure(input.Username)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 		return
 	}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "JWT_SECRET not set"})
+	h.recordSuccess(input.Username)
+
+	if needsRehash {
+		h.rehashPassword(&user, input.Password)
+	}
+
+	h.completeLogin(c, &user)
+}
+
+// completeLogin starts a session for a user who proved their identity, or a
+// two-factor challenge first when the account has it enabled.
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User) {
+	twoFactorEnabled, err := h.twoFactor.IsEnabled(user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor authentication"})
 		return
 	}
 
-	accessTokenClaims := middleware.Claims{
-		UserID: user.UserID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
-		},
+	if twoFactorEnabled {
+		if err := services.SetTwoFactorChallenge(c, user.UserID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor challenge"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":             "Two-factor authentication required",
+			"two_factor_required": true,
+		})
+		return
 	}
-	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims).SignedString([]byte(jwtSecret))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+
+	if _, err := h.sessions.StartSession(c, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
 		return
 	}
 
-	refreshTokenClaims := middleware.Claims{
-		UserID: user.UserID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
-		},
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+	})
+}
+
+// VerifyTwoFactorLogin is the second login step for accounts with two-factor
+// authentication. It accepts an authenticator code or a recovery code.
+func (h *AuthHandler) VerifyTwoFactorLogin(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenClaims).SignedString([]byte(jwtSecret))
+
+	userID, err := services.TwoFactorChallengeUserID(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Two-factor challenge missing or expired, please login again"})
+		return
+	}
+
+	twoFactorAccount := fmt.Sprintf("2fa:%d", userID)
+	if !h.checkThrottle(c, twoFactorAccount) {
+		return
+	}
+
+	if err := h.twoFactor.Verify(userID, input.Code); err != nil {
+		h.recordFailure(twoFactorAccount)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid two-factor code"})
 		return
 	}
 
-	services.SetCookie(c, "refresh_token", refreshToken, 7*24*60*60)
-	services.SetCookie(c, "access_token", accessToken, 15*60)
+	h.recordSuccess(twoFactorAccount)
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil || user.IsDeleted {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	services.ClearTwoFactorChallenge(c)
+
+	if _, err := h.sessions.StartSession(c, &user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Login successful",
@@ -126,10 +209,73 @@ func (h *AuthHandler) Login(c *gin.Context) {
 }
 
 func (h *AuthHandler) Logout(c *gin.Context) {
-	services.SetCookie(c, "refresh_token", "", -1)
-	services.SetCookie(c, "access_token", "", -1)
+	if sessionID, exists := c.Get("session_id"); exists {
+		if err := h.sessions.RevokeSession(sessionID.(uint)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+			return
+		}
+	}
+
+	services.ClearAuthCookies(c)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Logout successful",
 	})
 }
+
+// checkThrottle answers 429 and returns false while the account is locked out
+// after too many failed attempts.
+func (h *AuthHandler) checkThrottle(c *gin.Context, account string) bool {
+	retryAfter, err := h.throttle.RetryAfter(account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return false
+	}
+
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(services.RetryAfterSeconds(retryAfter)))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed attempts, please try again later"})
+		return false
+	}
+
+	return true
+}
+
+func (h *AuthHandler) recordFailure(account string) {
+	if err := h.throttle.RecordFailure(account); err != nil {
+		log.Printf("Error recording failed login for %s: %v", account, err)
+	}
+}
+
+func (h *AuthHandler) recordSuccess(account string) {
+	if err := h.throttle.RecordSuccess(account); err != nil {
+		log.Printf("Error resetting failed logins for %s: %v", account, err)
+	}
+}
+
+// verifyDummyPassword spends as long as checking a real password, so failed
+// logins do not reveal whether the username exists.
+func (h *AuthHandler) verifyDummyPassword(password string) {
+	if h.dummyHash != "" {
+		h.hasher.Verify(password, h.dummyHash)
+	}
+}
+
+// rehashPassword upgrades a hash made with an older algorithm or weaker
+// parameters. Failures are only logged since the login itself succeeded.
+func (h *AuthHandler) rehashPassword(user *models.User, password string) {
+	hashedPassword, err := h.hasher.Hash(password)
+	if err != nil {
+		log.Printf("Error rehashing password of user %d: %v", user.UserID, err)
+		return
+	}
+
+	if err := h.db.Model(&models.User{}).
+		Where("user_id = ? AND password_hash = ?", user.UserID, user.PasswordHash).
+		Update("password_hash", hashedPassword).Error; err != nil {
+		log.Printf("Error rehashing password of user %d: %v", user.UserID, err)
+		return
+	}
+
+	user.PasswordHash = hashedPassword
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+)
+
+// RequestMagicLink emails a single-use sign-in link to a verified address.
+func (h *AuthHandler) RequestMagicLink(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// As with ForgotPassword, the response does not reveal whether the
+	// address belongs to an account.
+	response := gin.H{"message": "If a verified account uses this email, a sign-in link has been sent"}
+
+	var user models.User
+	if err := h.db.Where("email = ? AND email_verified_at IS NOT NULL", services.NormalizeEmail(input.Email)).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if user.IsDeleted {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if err := services.SendMagicLinkEmail(c.Request.Context(), h.mailer, h.emailTokens, &user, *user.Email); err != nil {
+		log.Printf("Error sending magic link email to user %d: %v", user.UserID, err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// VerifyMagicLink redeems a sign-in link and logs the user in the same way as
+// Login, including the two-factor step.
+func (h *AuthHandler) VerifyMagicLink(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	emailToken, err := h.emailTokens.Consume(input.Token, services.EmailTokenMagicLink)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired sign-in link"})
+		return
+	}
+
+	// The link only works while it was sent to the account's current address.
+	var user models.User
+	if err := h.db.Where("user_id = ? AND email = ?", emailToken.UserID, emailToken.Email).First(&user).Error; err != nil || user.IsDeleted {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired sign-in link"})
+		return
+	}
+
+	h.completeLogin(c, &user)
+}
@@ -2,116 +2,271 @@ package auth
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"net/http"
-	"os"
-	"time"
+	"net/url"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/oadultradeepfield/olympliance-server/internal/middleware"
 	"github.com/oadultradeepfield/olympliance-server/internal/models"
 	"github.com/oadultradeepfield/olympliance-server/internal/services"
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
+	"gorm.io/gorm"
 )
 
-var googleOAuth2Config = oauth2.Config{
-	ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-	ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-	RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
-	Scopes: []string{
-		"https://www.googleapis.com/auth/userinfo.profile",
-		"https://www.googleapis.com/auth/userinfo.email",
-	},
-	Endpoint: google.Endpoint,
+var errLastLoginMethod = errors.New("cannot remove the last login method")
+
+func (h *AuthHandler) OAuthLogin(c *gin.Context) {
+	h.startOAuthFlow(c, 0)
 }
 
-func (h *AuthHandler) GoogleLogin(c *gin.Context) {
-	url := googleOAuth2Config.AuthCodeURL("", oauth2.AccessTypeOffline)
-	c.Redirect(http.StatusFound, url)
+// OAuthLink starts the provider flow for the signed-in user so that the
+// callback links the provider identity to their account.
+func (h *AuthHandler) OAuthLink(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	h.startOAuthFlow(c, currentUser.UserID)
+}
+
+func (h *AuthHandler) startOAuthFlow(c *gin.Context, linkUserID uint) {
+	provider, err := h.providers.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown sign-in provider"})
+		return
+	}
+
+	returnTo, err := services.ResolveReturnTo(c.Query("return_to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "return_to is not allowed"})
+		return
+	}
+
+	state, err := services.NewOAuthState(provider.Name(), returnTo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate OAuth state"})
+		return
+	}
+	state.LinkUserID = linkUserID
+
+	if err := services.SetOAuthStateCookie(c, state); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store OAuth state"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, provider.AuthCodeURL(state))
 }
 
-func (h *AuthHandler) GoogleCallback(c *gin.Context) {
+func (h *AuthHandler) OAuthCallback(c *gin.Context) {
+	provider, err := h.providers.Get(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown sign-in provider"})
+		return
+	}
+
+	state, err := services.ConsumeOAuthStateCookie(c, provider.Name(), c.Query("state"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired OAuth state"})
+		return
+	}
+
 	code := c.DefaultQuery("code", "")
 	if code == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Code is missing"})
 		return
 	}
 
-	token, err := googleOAuth2Config.Exchange(context.Background(), code)
+	userInfo, err := provider.Exchange(context.Background(), code, state)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to exchange token"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to verify sign-in with provider"})
 		return
 	}
 
-	client := googleOAuth2Config.Client(context.Background(), token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v3/userinfo")
+	if state.LinkUserID != 0 {
+		h.linkOAuthIdentity(c, provider.Name(), state, userInfo)
+		return
+	}
+
+	user, err := h.findOrCreateOAuthUser(provider.Name(), userInfo)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user info"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
-	defer resp.Body.Close()
 
-	var userInfo struct {
-		Name string `json:"name"`
-		Sub  string `json:"sub"`
+	if user.IsDeleted {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account has been deleted"})
+		return
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse user info"})
+	twoFactorEnabled, err := h.twoFactor.IsEnabled(user.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor authentication"})
 		return
 	}
 
-	var user models.User
-	if err := h.db.Where("google_id = ?", userInfo.Sub).First(&user).Error; err != nil {
-		if err := h.db.Create(&models.User{
-			GoogleID:  userInfo.Sub,
-			Username:  userInfo.Name,
-			CreatedAt: time.Now(),
-		}).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	// The frontend finishes the login through /api/login/2fa, which reads the
+	// challenge cookie set here.
+	if twoFactorEnabled {
+		if err := services.SetTwoFactorChallenge(c, user.UserID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor challenge"})
 			return
 		}
-		if err := h.db.Where("google_id = ?", userInfo.Sub).First(&user).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch created user"})
+
+		returnTo, err := url.Parse(state.ReturnTo)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid return URL"})
 			return
 		}
+		query := returnTo.Query()
+		query.Set("two_factor_required", "true")
+		returnTo.RawQuery = query.Encode()
+
+		c.Redirect(http.StatusFound, returnTo.String())
+		return
 	}
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "JWT_SECRET not set"})
+	if _, err := h.sessions.StartSession(c, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
 		return
 	}
 
-	accessTokenClaims := middleware.Claims{
-		UserID: user.UserID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
-		},
+	c.Redirect(http.StatusFound, state.ReturnTo)
+}
+
+func (h *AuthHandler) findOrCreateOAuthUser(provider string, userInfo *services.OAuthUserInfo) (*models.User, error) {
+	var identity models.Identity
+	err := h.db.Where("provider = ? AND subject = ?", provider, userInfo.Subject).First(&identity).Error
+	if err == nil {
+		var user models.User
+		if err := h.db.First(&user, identity.UserID).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
 	}
-	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims).SignedString([]byte(jwtSecret))
+
+	var user models.User
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		username, err := services.GenerateUsername(tx, userInfo.Name)
+		if err != nil {
+			return err
+		}
+
+		user = models.User{
+			Username:        username,
+			NeedsOnboarding: true,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.Identity{
+			UserID:   user.UserID,
+			Provider: provider,
+			Subject:  userInfo.Subject,
+			Email:    userInfo.Email,
+		}).Error
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (h *AuthHandler) linkOAuthIdentity(c *gin.Context, provider string, state *services.OAuthState, userInfo *services.OAuthUserInfo) {
+	var user models.User
+	if err := h.db.First(&user, state.LinkUserID).Error; err != nil || user.IsDeleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
-	refreshTokenClaims := middleware.Claims{
-		UserID: user.UserID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
-		},
+	var existingIdentity models.Identity
+	if err := h.db.Where("provider = ? AND subject = ?", provider, userInfo.Subject).First(&existingIdentity).Error; err == nil {
+		if existingIdentity.UserID == user.UserID {
+			c.Redirect(http.StatusFound, state.ReturnTo)
+			return
+		}
+		c.JSON(http.StatusConflict, gin.H{"error": "This identity is already linked to another account"})
+		return
 	}
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenClaims).SignedString([]byte(jwtSecret))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+
+	if err := h.db.Where("user_id = ? AND provider = ?", user.UserID, provider).First(&existingIdentity).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another identity from this provider is already linked"})
 		return
 	}
 
-	services.SetCookie(c, "refresh_token", refreshToken, 7*24*60*60)
-	services.SetCookie(c, "access_token", accessToken, 15*60)
+	if err := h.db.Create(&models.Identity{
+		UserID:   user.UserID,
+		Provider: provider,
+		Subject:  userInfo.Subject,
+		Email:    userInfo.Email,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link identity"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, state.ReturnTo)
+}
+
+func (h *AuthHandler) UnlinkProvider(c *gin.Context) {
+	provider := c.Param("provider")
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
 
-	redirectURL := os.Getenv("FRONTEND_REDIRECT_URL")
-	c.Redirect(http.StatusFound, redirectURL)
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var identity models.Identity
+		if err := tx.Where("user_id = ? AND provider = ?", currentUser.UserID, provider).First(&identity).Error; err != nil {
+			return err
+		}
+
+		var otherIdentities int64
+		if err := tx.Model(&models.Identity{}).
+			Where("user_id = ? AND identity_id <> ?", currentUser.UserID, identity.IdentityID).
+			Count(&otherIdentities).Error; err != nil {
+			return err
+		}
+
+		var passkeys int64
+		if err := tx.Model(&models.WebAuthnCredential{}).Where("user_id = ?", currentUser.UserID).Count(&passkeys).Error; err != nil {
+			return err
+		}
+
+		if currentUser.PasswordHash == "" && otherIdentities+passkeys == 0 {
+			return errLastLoginMethod
+		}
+
+		return tx.Delete(&identity).Error
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "No identity linked for this provider"})
+	case errors.Is(err, errLastLoginMethod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set a password, link another provider, or add a passkey before unlinking the last login method"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlink identity"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked successfully"})
+	}
 }
@@ -0,0 +1,365 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	testClientID    = "olympliance-test"
+	testFrontendURL = "http://frontend.test/"
+)
+
+// fakeOIDCServer is an OpenID provider serving discovery, authorization
+// codes, tokens, JWKS and user info. Tokens are signed with key unless
+// signWith is set, and mutate may tamper with the ID token claims. Like
+// Microsoft's common endpoint, a {tenantid} issuer is discovered under
+// "common".
+type fakeOIDCServer struct {
+	*httptest.Server
+	issuer       string
+	discoveryURL string
+	key          *rsa.PrivateKey
+	signWith     *rsa.PrivateKey
+	mutate       func(jwt.MapClaims)
+
+	nonce     string
+	challenge string
+}
+
+func newFakeOIDCServer(t *testing.T, issuerPath string) *fakeOIDCServer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeOIDCServer{key: key}
+	mux := http.NewServeMux()
+	fake.Server = httptest.NewServer(mux)
+	t.Cleanup(fake.Close)
+	fake.issuer = fake.URL + issuerPath
+	fake.discoveryURL = fake.URL + strings.ReplaceAll(issuerPath, "{tenantid}", "common")
+
+	mux.HandleFunc(strings.TrimPrefix(fake.discoveryURL, fake.URL)+"/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]string{
+			"issuer":                 fake.issuer,
+			"authorization_endpoint": fake.URL + "/authorize",
+			"token_endpoint":         fake.URL + "/token",
+			"userinfo_endpoint":      fake.URL + "/userinfo",
+			"jwks_uri":               fake.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]interface{}{"keys": []services.JWK{{
+			KeyType:   "RSA",
+			KeyID:     "test-key",
+			Use:       "sig",
+			Algorithm: "RS256",
+			N:         base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if r.PostFormValue("code") != "test-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != fake.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			writeTestJSON(w, map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		now := time.Now()
+		claims := jwt.MapClaims{
+			"iss":   fake.issuer,
+			"sub":   "subject-1",
+			"aud":   testClientID,
+			"nonce": fake.nonce,
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+			"name":  "Ada Lovelace",
+			"email": "ada@example.com",
+		}
+		if fake.mutate != nil {
+			fake.mutate(claims)
+		}
+
+		signWith := fake.key
+		if fake.signWith != nil {
+			signWith = fake.signWith
+		}
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		idToken.Header["kid"] = "test-key"
+		signed, err := idToken.SignedString(signWith)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		writeTestJSON(w, map[string]interface{}{
+			"access_token": "test-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     signed,
+		})
+	})
+
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeTestJSON(w, map[string]string{"sub": "subject-1", "name": "Ada Lovelace", "email": "ada@example.com"})
+	})
+
+	return fake
+}
+
+func writeTestJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func newOAuthTestRouter(t *testing.T, fake *fakeOIDCServer) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("FRONTEND_REDIRECT_URL", testFrontendURL)
+	t.Setenv("BACKEND_DOMAIN", "localhost")
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to an in-memory database gets its own database.
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&models.User{}, &models.Identity{}, &models.Session{}, &models.TwoFactor{}); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := services.NewOIDCProvider(context.Background(), "fake", fake.discoveryURL, oauth2.Config{
+		ClientID:     testClientID,
+		ClientSecret: "test-client-secret",
+		RedirectURL:  "http://backend.test/api/auth/fake/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	providers := services.NewOAuthProviderRegistry()
+	providers.Register(provider)
+
+	handler := &AuthHandler{
+		db:        db,
+		sessions:  services.NewSessionManager(db),
+		providers: providers,
+		twoFactor: services.NewTwoFactorManager(db),
+	}
+
+	r := gin.New()
+	r.GET("/api/auth/:provider/", handler.OAuthLogin)
+	r.GET("/api/auth/:provider/callback", handler.OAuthCallback)
+	return r, db
+}
+
+// signInWithFake starts the flow, plays the provider's authorization step and
+// returns the response of the callback.
+func signInWithFake(t *testing.T, r *gin.Engine, fake *fakeOIDCServer) *httptest.ResponseRecorder {
+	t.Helper()
+
+	start := httptest.NewRecorder()
+	r.ServeHTTP(start, httptest.NewRequest(http.MethodGet, "/api/auth/fake/", nil))
+	if start.Code != http.StatusFound {
+		t.Fatalf("login returned %d: %s", start.Code, start.Body)
+	}
+
+	authorize, err := url.Parse(start.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(authorize.String(), fake.URL+"/authorize") {
+		t.Fatalf("login redirected to %s", authorize)
+	}
+	query := authorize.Query()
+	if query.Get("client_id") != testClientID || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected authorization request %s", authorize)
+	}
+	fake.nonce = query.Get("nonce")
+	fake.challenge = query.Get("code_challenge")
+
+	callback := httptest.NewRequest(http.MethodGet,
+		"/api/auth/fake/callback?code=test-code&state="+url.QueryEscape(query.Get("state")), nil)
+	for _, cookie := range start.Result().Cookies() {
+		callback.AddCookie(cookie)
+	}
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, callback)
+	return recorder
+}
+
+func hasCookie(recorder *httptest.ResponseRecorder, name string) bool {
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == name && cookie.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOAuthCallbackSignsInWithVerifiedIDToken(t *testing.T) {
+	fake := newFakeOIDCServer(t, "")
+	r, db := newOAuthTestRouter(t, fake)
+
+	recorder := signInWithFake(t, r, fake)
+	if recorder.Code != http.StatusFound || recorder.Header().Get("Location") != testFrontendURL {
+		t.Fatalf("callback returned %d to %q: %s", recorder.Code, recorder.Header().Get("Location"), recorder.Body)
+	}
+	if !hasCookie(recorder, "access_token") || !hasCookie(recorder, "refresh_token") {
+		t.Fatal("callback did not start a session")
+	}
+
+	var identity models.Identity
+	if err := db.Where("provider = ? AND subject = ?", "fake", "subject-1").First(&identity).Error; err != nil {
+		t.Fatalf("identity not created: %v", err)
+	}
+	if identity.Email != "ada@example.com" {
+		t.Errorf("identity email = %q", identity.Email)
+	}
+
+	// Signing in again reuses the account.
+	if recorder := signInWithFake(t, r, fake); recorder.Code != http.StatusFound {
+		t.Fatalf("second callback returned %d: %s", recorder.Code, recorder.Body)
+	}
+	var users int64
+	db.Model(&models.User{}).Count(&users)
+	if users != 1 {
+		t.Errorf("users = %d, want 1", users)
+	}
+}
+
+func TestOAuthCallbackRejectsInvalidIDTokens(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		signWith *rsa.PrivateKey
+		mutate   func(jwt.MapClaims)
+	}{
+		{name: "forged signature", signWith: otherKey},
+		{name: "expired", mutate: func(claims jwt.MapClaims) {
+			claims["iat"] = time.Now().Add(-2 * time.Hour).Unix()
+			claims["exp"] = time.Now().Add(-time.Hour).Unix()
+		}},
+		{name: "no expiry", mutate: func(claims jwt.MapClaims) { delete(claims, "exp") }},
+		{name: "no issue time", mutate: func(claims jwt.MapClaims) { delete(claims, "iat") }},
+		{name: "issued in the future", mutate: func(claims jwt.MapClaims) {
+			claims["iat"] = time.Now().Add(time.Hour).Unix()
+		}},
+		{name: "wrong issuer", mutate: func(claims jwt.MapClaims) { claims["iss"] = "https://evil.test" }},
+		{name: "wrong audience", mutate: func(claims jwt.MapClaims) { claims["aud"] = "someone-else" }},
+		{name: "wrong nonce", mutate: func(claims jwt.MapClaims) { claims["nonce"] = "replayed" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeOIDCServer(t, "")
+			fake.signWith, fake.mutate = tt.signWith, tt.mutate
+			r, db := newOAuthTestRouter(t, fake)
+
+			recorder := signInWithFake(t, r, fake)
+			if recorder.Code != http.StatusUnauthorized {
+				t.Fatalf("callback returned %d, want 401: %s", recorder.Code, recorder.Body)
+			}
+			if hasCookie(recorder, "access_token") {
+				t.Error("callback started a session")
+			}
+
+			var users int64
+			db.Model(&models.User{}).Count(&users)
+			if users != 0 {
+				t.Errorf("users = %d, want 0", users)
+			}
+		})
+	}
+}
+
+func TestOAuthCallbackChecksTenantIssuer(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(fake *fakeOIDCServer) func(jwt.MapClaims)
+		want   int
+	}{
+		{
+			name: "matching tenant",
+			mutate: func(fake *fakeOIDCServer) func(jwt.MapClaims) {
+				return func(claims jwt.MapClaims) {
+					claims["iss"] = fake.URL + "/tenant-a/v2.0"
+					claims["tid"] = "tenant-a"
+				}
+			},
+			want: http.StatusFound,
+		},
+		{
+			name: "issuer from another tenant",
+			mutate: func(fake *fakeOIDCServer) func(jwt.MapClaims) {
+				return func(claims jwt.MapClaims) {
+					claims["iss"] = fake.URL + "/tenant-b/v2.0"
+					claims["tid"] = "tenant-a"
+				}
+			},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "no tenant",
+			mutate: func(fake *fakeOIDCServer) func(jwt.MapClaims) {
+				return func(claims jwt.MapClaims) {
+					claims["iss"] = fake.URL + "/{tenantid}/v2.0"
+				}
+			},
+			want: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeOIDCServer(t, "/{tenantid}/v2.0")
+			fake.mutate = tt.mutate(fake)
+			r, _ := newOAuthTestRouter(t, fake)
+
+			if recorder := signInWithFake(t, r, fake); recorder.Code != tt.want {
+				t.Fatalf("callback returned %d, want %d: %s", recorder.Code, tt.want, recorder.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+)
+
+// BeginPasskeyLogin returns the options the frontend passes to
+// navigator.credentials.get. The body is optional; a username limits the
+// login to that user's passkeys.
+func (h *AuthHandler) BeginPasskeyLogin(c *gin.Context) {
+	var input struct {
+		Username string `json:"username"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	assertion, err := h.passkeys.BeginLogin(c, strings.TrimSpace(input.Username))
+	if errors.Is(err, services.ErrPasskeysNotConfigured) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Passkeys are not configured"})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidPasskey) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No passkey is registered for this username"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passkey login"})
+		return
+	}
+
+	c.JSON(http.StatusOK, assertion)
+}
+
+// FinishPasskeyLogin takes the assertion returned by navigator.credentials.get
+// as the request body. Passkeys require user verification on the device, so
+// they already count as two factors and skip the two-factor step.
+func (h *AuthHandler) FinishPasskeyLogin(c *gin.Context) {
+	user, err := h.passkeys.FinishLogin(c)
+	switch {
+	case errors.Is(err, services.ErrPasskeysNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Passkeys are not configured"})
+		return
+	case errors.Is(err, services.ErrInvalidPasskey):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Passkey login failed or expired, please try again"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify passkey"})
+		return
+	}
+
+	if user.IsDeleted {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Passkey login failed or expired, please try again"})
+		return
+	}
+
+	if _, err := h.sessions.StartSession(c, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Login successful",
+	})
+}
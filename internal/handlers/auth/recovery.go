@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+)
+
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var input struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	emailToken, err := h.emailTokens.Consume(input.Token, services.EmailTokenVerifyEmail)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	// The address may have changed since the link was sent.
+	result := h.db.Model(&models.User{}).
+		Where("user_id = ? AND email = ?", emailToken.UserID, emailToken.Email).
+		Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired verification link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The response is the same whether or not the address is known, so this
+	// endpoint cannot be used to find out who has an account.
+	response := gin.H{"message": "If a verified account uses this email, a reset link has been sent"}
+
+	var user models.User
+	if err := h.db.Where("email = ? AND email_verified_at IS NOT NULL", services.NormalizeEmail(input.Email)).First(&user).Error; err != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if user.IsDeleted {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if err := services.SendPasswordResetEmail(c.Request.Context(), h.mailer, h.emailTokens, &user, *user.Email); err != nil {
+		log.Printf("Error sending password reset email to user %d: %v", user.UserID, err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var input struct {
+		Token           string `json:"token" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required"`
+		ConfirmPassword string `json:"confirm_password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.NewPassword != input.ConfirmPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password and confirmation do not match"})
+		return
+	}
+
+	emailToken, err := h.emailTokens.Peek(input.Token, services.EmailTokenResetPassword)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset link"})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, emailToken.UserID).Error; err != nil || user.IsDeleted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset link"})
+		return
+	}
+
+	if err := services.CheckPassword(input.NewPassword, services.UserIdentifiers(&user)...); err != nil {
+		services.RespondPasswordError(c, err)
+		return
+	}
+
+	if _, err := h.emailTokens.Consume(input.Token, services.EmailTokenResetPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired reset link"})
+		return
+	}
+
+	hashedPassword, err := h.hasher.Hash(input.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash new password"})
+		return
+	}
+
+	user.PasswordHash = hashedPassword
+	if err := h.db.Save(&user).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+
+	if err := h.sessions.RevokeUserSessions(user.UserID, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
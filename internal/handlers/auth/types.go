@@ -1,11 +1,45 @@
 package auth
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"log"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
 
 type AuthHandler struct {
-	db *gorm.DB
+	db          *gorm.DB
+	sessions    *services.SessionManager
+	providers   *services.OAuthProviderRegistry
+	twoFactor   *services.TwoFactorManager
+	emailTokens *services.EmailTokenManager
+	mailer      services.Mailer
+	throttle    *services.LoginThrottle
+	hasher      services.PasswordHasher
+	passkeys    *services.PasskeyManager
+	// dummyHash is verified against when the username does not exist, so the
+	// response takes as long as for a wrong password.
+	dummyHash string
 }
 
-func NewAuthHandler(db *gorm.DB) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(db *gorm.DB, attempts services.AttemptStore, passkeys *services.PasskeyManager) *AuthHandler {
+	hasher := services.NewPasswordHasherFromEnv()
+	dummyHash, err := hasher.Hash("olympliance-dummy-password")
+	if err != nil {
+		log.Printf("Error hashing dummy password: %v", err)
+	}
+
+	return &AuthHandler{
+		db:          db,
+		sessions:    services.NewSessionManager(db),
+		providers:   services.LoadOAuthProviders(context.Background()),
+		twoFactor:   services.NewTwoFactorManager(db),
+		emailTokens: services.NewEmailTokenManager(db),
+		mailer:      services.NewMailerFromEnv(),
+		throttle:    services.NewLoginThrottle(attempts),
+		hasher:      hasher,
+		passkeys:    passkeys,
+		dummyHash:   dummyHash,
+	}
 }
@@ -0,0 +1,245 @@
+package automod
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+type ruleInput struct {
+	Name       string   `json:"name" binding:"required,max=100"`
+	Type       string   `json:"type" binding:"required"`
+	Keywords   []string `json:"keywords"`
+	Pattern    string   `json:"pattern"`
+	Threshold  float64  `json:"threshold"`
+	CategoryID *uint    `json:"category_id"`
+	Action     string   `json:"action" binding:"required"`
+	Message    string   `json:"message" binding:"max=500"`
+	IsEnabled  *bool    `json:"is_enabled"`
+}
+
+// apply copies the input onto the rule. Rules stay enabled unless is_enabled
+// is false.
+func (input *ruleInput) apply(rule *models.AutomodRule) {
+	rule.Name = strings.TrimSpace(input.Name)
+	rule.Type = input.Type
+	rule.Keywords = input.Keywords
+	rule.Pattern = input.Pattern
+	rule.Threshold = input.Threshold
+	rule.CategoryID = input.CategoryID
+	rule.Action = input.Action
+	rule.Message = strings.TrimSpace(input.Message)
+	if input.IsEnabled != nil {
+		rule.IsEnabled = *input.IsEnabled
+	}
+}
+
+func (h *AutomodHandler) CreateRule(c *gin.Context) {
+	var input ruleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	rule := models.AutomodRule{IsEnabled: true, CreatedBy: currentUser.UserID}
+	input.apply(&rule)
+
+	if !h.saveRule(c, &rule, func(tx *gorm.DB) error {
+		return services.RecordModeration(tx, currentUser, services.ModerationEntry{
+			Action:     services.ModerationCreateRule,
+			TargetType: services.ModerationTargetRule,
+			TargetID:   rule.AutomodRuleID,
+			After:      rule,
+		})
+	}) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"rule": rule})
+}
+
+func (h *AutomodHandler) UpdateRule(c *gin.Context) {
+	ruleID := c.Param("id")
+
+	var input ruleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	var rule models.AutomodRule
+	if err := h.db.First(&rule, ruleID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Automod rule not found"})
+		return
+	}
+
+	before := rule
+	input.apply(&rule)
+
+	if !h.saveRule(c, &rule, func(tx *gorm.DB) error {
+		return services.RecordModeration(tx, currentUser, services.ModerationEntry{
+			Action:     services.ModerationUpdateRule,
+			TargetType: services.ModerationTargetRule,
+			TargetID:   rule.AutomodRuleID,
+			Before:     before,
+			After:      rule,
+		})
+	}) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rule": rule})
+}
+
+func (h *AutomodHandler) DeleteRule(c *gin.Context) {
+	ruleID := c.Param("id")
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	var rule models.AutomodRule
+	if err := h.db.First(&rule, ruleID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Automod rule not found"})
+		return
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&rule).Error; err != nil {
+			return err
+		}
+		return services.RecordModeration(tx, currentUser, services.ModerationEntry{
+			Action:     services.ModerationDeleteRule,
+			TargetType: services.ModerationTargetRule,
+			TargetID:   rule.AutomodRuleID,
+			Before:     rule,
+		})
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete automod rule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Automod rule deleted"})
+}
+
+// TestRules runs sample text through a draft rule, one saved rule, or every
+// enabled rule that applies in the category, without saving anything.
+func (h *AutomodHandler) TestRules(c *gin.Context) {
+	var input struct {
+		Title           string     `json:"title"`
+		Content         string     `json:"content" binding:"required"`
+		CategoryID      uint       `json:"category_id"`
+		AccountAgeHours *float64   `json:"account_age_hours" binding:"omitempty,min=0"`
+		RuleID          *uint      `json:"rule_id"`
+		Rule            *ruleInput `json:"rule"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var rules []models.AutomodRule
+	switch {
+	case input.Rule != nil:
+		rule := models.AutomodRule{IsEnabled: true}
+		input.Rule.apply(&rule)
+		if err := services.ValidateAutomodRule(&rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		rules = []models.AutomodRule{rule}
+	case input.RuleID != nil:
+		var rule models.AutomodRule
+		if err := h.db.First(&rule, *input.RuleID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Automod rule not found"})
+			return
+		}
+		rules = []models.AutomodRule{rule}
+	default:
+		scoped, err := h.automod.ScopedRules(input.CategoryID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch automod rules"})
+			return
+		}
+		rules = scoped
+	}
+
+	post := services.AutomodPost{CategoryID: input.CategoryID, Text: input.Content}
+	if input.Title != "" {
+		post.Text = input.Title + "\n\n" + input.Content
+	}
+	if input.AccountAgeHours != nil {
+		createdAt := time.Now().Add(-time.Duration(*input.AccountAgeHours * float64(time.Hour)))
+		post.AccountCreatedAt = &createdAt
+	}
+
+	verdict := services.EvaluateAutomodRules(rules, post)
+	c.JSON(http.StatusOK, gin.H{
+		"action":   verdict.Action,
+		"matches":  verdict.Hits,
+		"messages": verdict.Messages(),
+	})
+}
+
+// saveRule validates and stores the rule, and logs it with record in the same
+// transaction. It writes the error response itself and reports whether to
+// continue.
+func (h *AutomodHandler) saveRule(c *gin.Context, rule *models.AutomodRule, record func(tx *gorm.DB) error) bool {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := services.NewAutomodManager(tx).SaveRule(rule); err != nil {
+			return err
+		}
+		return record(tx)
+	})
+	switch {
+	case errors.Is(err, services.ErrInvalidAutomodRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	case errors.Is(err, services.ErrUnknownCategory):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found"})
+		return false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save automod rule"})
+		return false
+	}
+	return true
+}
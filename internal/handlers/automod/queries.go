@@ -0,0 +1,75 @@
+package automod
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+func (h *AutomodHandler) GetRules(c *gin.Context) {
+	rules, err := h.automod.Rules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch automod rules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// GetMatches lists automod matches, newest first, filtered by rule_id,
+// user_id and action.
+func (h *AutomodHandler) GetMatches(c *gin.Context) {
+	query := h.db.Model(&models.AutomodMatch{})
+
+	if ruleID := c.Query("rule_id"); ruleID != "" {
+		query = query.Where("rule_id = ?", ruleID)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if action := c.Query("action"); action != "" {
+		if !services.Contains(services.AutomodActions, action) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid action"})
+			return
+		}
+		query = query.Where("action = ?", action)
+	}
+
+	page := c.DefaultQuery("page", "1")
+	perPage := c.DefaultQuery("per_page", "50")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil || pageInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	perPageInt, err := strconv.Atoi(perPage)
+	if err != nil || perPageInt < 1 || perPageInt > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid per_page number"})
+		return
+	}
+
+	// The filters are shared by the count and the page query.
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count automod matches"})
+		return
+	}
+
+	matches := []models.AutomodMatch{}
+	if err := query.Order("created_at DESC, automod_match_id DESC").
+		Limit(perPageInt).
+		Offset((pageInt - 1) * perPageInt).
+		Find(&matches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch automod matches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"matches": matches, "total": total})
+}
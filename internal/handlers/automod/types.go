@@ -0,0 +1,18 @@
+package automod
+
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+type AutomodHandler struct {
+	db      *gorm.DB
+	automod *services.AutomodManager
+}
+
+func NewAutomodHandler(db *gorm.DB) *AutomodHandler {
+	return &AutomodHandler{
+		db:      db,
+		automod: services.NewAutomodManager(db),
+	}
+}
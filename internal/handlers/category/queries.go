@@ -0,0 +1,75 @@
+package category
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+)
+
+// GetCategoryModerators lists the moderators of each category, optionally only
+// of the category given as category_id. Forum moderators moderate every
+// category and are listed once.
+func (h *CategoryHandler) GetCategoryModerators(c *gin.Context) {
+	query := h.db.Order("category_id")
+
+	var categoryID uint64
+	if value := c.Query("category_id"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+			return
+		}
+		categoryID = parsed
+		query = query.Where("category_id = ?", categoryID)
+	}
+
+	var categories []models.Category
+	if err := query.Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	if categoryID != 0 && len(categories) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	moderators, err := h.rbac.CategoryModerators(uint(categoryID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category moderators"})
+		return
+	}
+
+	forumModerators, err := h.rbac.ForumModerators()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch forum moderators"})
+		return
+	}
+
+	byCategory := map[uint][]services.CategoryModeratorInfo{}
+	for _, moderator := range moderators {
+		byCategory[moderator.CategoryID] = append(byCategory[moderator.CategoryID], moderator)
+	}
+
+	response := make([]gin.H, len(categories))
+	for i, category := range categories {
+		categoryModerators := byCategory[category.CategoryID]
+		if categoryModerators == nil {
+			categoryModerators = []services.CategoryModeratorInfo{}
+		}
+
+		response[i] = gin.H{
+			"category_id": category.CategoryID,
+			"name":        category.Name,
+			"moderators":  categoryModerators,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories":       response,
+		"forum_moderators": forumModerators,
+	})
+}
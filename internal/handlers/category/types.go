@@ -0,0 +1,15 @@
+package category
+
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+type CategoryHandler struct {
+	db   *gorm.DB
+	rbac *services.RBACManager
+}
+
+func NewCategoryHandler(db *gorm.DB) *CategoryHandler {
+	return &CategoryHandler{db: db, rbac: services.NewRBACManager(db)}
+}
@@ -1,13 +1,19 @@
 package comment
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/middleware"
 	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
 	"gorm.io/gorm"
 )
 
+var errAlreadyDeleted = errors.New("comment already deleted")
+
 func (h *CommentHandler) CreateComment(c *gin.Context) {
 	var input struct {
 		ThreadID        *uint  `json:"thread_id" binding:"required"`
@@ -37,27 +43,54 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
 		return
 	}
 
+	var thread models.Thread
+	if err := h.db.Scopes(services.VisibleTo(currentUser)).First(&thread, *input.ThreadID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thread not found"})
+		return
+	}
+
 	comment := models.Comment{
-		UserID:  currentUser.UserID,
-		Content: input.Content,
+		ThreadID:   *input.ThreadID,
+		UserID:     currentUser.UserID,
+		Content:    input.Content,
+		Visibility: services.VisibilityVisible,
 	}
 
-	if input.ThreadID != nil {
-		comment.ThreadID = *input.ThreadID
-		if err := h.db.Model(&models.Thread{}).
-			Where("thread_id = ?", *input.ThreadID).
-			Update("stats", gorm.Expr("jsonb_set(stats, '{comments}', to_jsonb((stats->>'comments')::int + 1)::text::jsonb)")).
-			Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update thread stats"})
+	post := automodPost(currentUser, thread.CategoryID, &comment)
+	verdict, ok := h.checkAutomod(c, post)
+	if !ok {
+		return
+	}
+	if verdict.Action == services.AutomodHold {
+		comment.Visibility = services.VisibilityHeld
+	} else {
+		pending, err := h.premoderation.IsRequired(currentUser)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check comment"})
 			return
 		}
+		if pending {
+			comment.Visibility = services.VisibilityPending
+		}
 	}
 
 	if input.ParentCommentID != nil {
 		comment.ParentCommentID = *input.ParentCommentID
 	}
 
-	if err := h.db.Create(&comment).Error; err != nil {
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+
+		if comment.Visibility == services.VisibilityVisible {
+			if err := services.AdjustCommentCount(tx, comment.ThreadID, 1); err != nil {
+				return err
+			}
+		}
+
+		return h.automod.Record(tx, verdict, post, comment.CommentID)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
 		return
 	}
@@ -103,7 +136,36 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
 		comment.Content = input.Content
 	}
 
-	if err := h.db.Save(&comment).Error; err != nil {
+	var thread models.Thread
+	if err := h.db.First(&thread, comment.ThreadID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thread not found"})
+		return
+	}
+
+	post := automodPost(currentUser, thread.CategoryID, &comment)
+	verdict, ok := h.checkAutomod(c, post)
+	if !ok {
+		return
+	}
+
+	// Only Hold changes the visibility here, which keeps any review of the
+	// comment since it was loaded.
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("visibility").Save(&comment).Error; err != nil {
+			return err
+		}
+
+		if verdict.Action == services.AutomodHold {
+			if err := h.automod.Hold(tx, services.ReportTargetComment, comment.CommentID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Select("visibility").First(&comment, comment.CommentID).Error; err != nil {
+			return err
+		}
+		return h.automod.Record(tx, verdict, post, comment.CommentID)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
 		return
 	}
@@ -132,16 +194,91 @@ func (h *CommentHandler) DeleteComment(c *gin.Context) {
 		return
 	}
 
-	if comment.UserID != currentUser.UserID && currentUser.RoleID <= 0 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to delete this comment"})
-		return
+	reason := services.RemovedByAuthor
+	if comment.UserID != currentUser.UserID {
+		var thread models.Thread
+		if err := h.db.First(&thread, comment.ThreadID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch thread"})
+			return
+		}
+
+		if !middleware.HasCategoryPermission(c, h.db, services.PermissionCommentDeleteAny, thread.CategoryID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to delete this comment"})
+			return
+		}
+		reason = services.RemovedByModerator
 	}
 
-	comment.IsDeleted = true
-	if err := h.db.Save(&comment).Error; err != nil {
+	// Deleting again would overwrite who removed the comment and why, which
+	// decides whether lifting a suspension restores it.
+	before := comment
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&comment).Where("is_deleted = ?", false).Updates(services.RemovalColumns(reason, currentUser.UserID, nil))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAlreadyDeleted
+		}
+
+		if reason != services.RemovedByModerator {
+			return nil
+		}
+
+		if err := tx.First(&comment, comment.CommentID).Error; err != nil {
+			return err
+		}
+
+		return services.RecordModeration(tx, currentUser, services.ModerationEntry{
+			Action:       services.ModerationDeleteComment,
+			TargetType:   services.ModerationTargetComment,
+			TargetID:     comment.CommentID,
+			TargetUserID: comment.UserID,
+			Reason:       strings.TrimSpace(c.Query("reason")),
+			Before:       before,
+			After:        comment,
+		})
+	})
+	if errors.Is(err, errAlreadyDeleted) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Comment has already been deleted"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
 }
+
+func automodPost(author *models.User, categoryID uint, comment *models.Comment) services.AutomodPost {
+	return services.AutomodPost{
+		TargetType:       services.ReportTargetComment,
+		UserID:           author.UserID,
+		CategoryID:       categoryID,
+		Text:             comment.Content,
+		AccountCreatedAt: &author.CreatedAt,
+	}
+}
+
+// checkAutomod runs the automod rules against a comment about to be saved and
+// records rejections. It writes the error response itself and reports whether
+// to continue.
+func (h *CommentHandler) checkAutomod(c *gin.Context, post services.AutomodPost) (*services.AutomodVerdict, bool) {
+	verdict, err := h.automod.Check(post)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check comment"})
+		return nil, false
+	}
+
+	if verdict.Action == services.AutomodReject {
+		if err := h.automod.Record(h.db, verdict, post, 0); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check comment"})
+			return nil, false
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Comment rejected", "reasons": verdict.Messages()})
+		return nil, false
+	}
+
+	return verdict, true
+}
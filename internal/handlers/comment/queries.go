@@ -38,7 +38,13 @@ func (h *CommentHandler) GetAllComments(c *gin.Context) {
 
 	offset := (pageInt - 1) * perPageInt
 
+	var viewer *models.User
+	if user, exists := c.Get("user"); exists {
+		viewer, _ = user.(*models.User)
+	}
+
 	query := h.db.Model(&models.Comment{}).
+		Scopes(services.VisibleTo(viewer)).
 		Limit(perPageInt).
 		Offset(offset)
 
@@ -1,11 +1,20 @@
 package comment
 
-import "gorm.io/gorm"
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
 
 type CommentHandler struct {
-	db *gorm.DB
+	db            *gorm.DB
+	automod       *services.AutomodManager
+	premoderation *services.PremoderationManager
 }
 
 func NewCommentHandler(db *gorm.DB) *CommentHandler {
-	return &CommentHandler{db: db}
+	return &CommentHandler{
+		db:            db,
+		automod:       services.NewAutomodManager(db),
+		premoderation: services.NewPremoderationManager(db),
+	}
 }
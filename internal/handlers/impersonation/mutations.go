@@ -0,0 +1,112 @@
+package impersonation
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+)
+
+// StartImpersonation issues a short-lived token that lets an admin see the
+// forum as another user.
+func (h *ImpersonationHandler) StartImpersonation(c *gin.Context) {
+	targetUserID := c.Param("id")
+
+	var input struct {
+		Reason          string `json:"reason" binding:"required"`
+		DurationMinutes int    `json:"duration_minutes"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	duration := services.DefaultImpersonationDuration
+	if input.DurationMinutes != 0 {
+		duration = time.Duration(input.DurationMinutes) * time.Minute
+	}
+	if duration <= 0 || duration > services.MaxImpersonationDuration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Duration must be between 1 and 60 minutes"})
+		return
+	}
+
+	var targetUser models.User
+	if err := h.db.First(&targetUser, targetUserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	token, impersonation, err := h.impersonations.Start(currentUser, &targetUser, reason, duration)
+	if errors.Is(err, services.ErrImpersonationDenied) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This user cannot be impersonated"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start impersonation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":       "Impersonation started, send the token as an Authorization: Bearer header",
+		"token":         token,
+		"impersonation": impersonation,
+	})
+}
+
+// EndImpersonation lets the admin who started an impersonation end it early.
+func (h *ImpersonationHandler) EndImpersonation(c *gin.Context) {
+	impersonationID := c.Param("id")
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	var impersonation models.Impersonation
+	if err := h.db.First(&impersonation, impersonationID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Impersonation not found"})
+		return
+	}
+
+	if impersonation.AdminID != currentUser.UserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the admin who started an impersonation can end it"})
+		return
+	}
+
+	if err := h.impersonations.End(impersonation.ImpersonationID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end impersonation"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Impersonation ended"})
+}
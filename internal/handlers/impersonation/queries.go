@@ -0,0 +1,52 @@
+package impersonation
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+)
+
+// GetImpersonations lists impersonations, newest first, optionally filtered
+// by admin_id and user_id.
+func (h *ImpersonationHandler) GetImpersonations(c *gin.Context) {
+	query := h.db.Model(&models.Impersonation{})
+	if adminID := c.Query("admin_id"); adminID != "" {
+		query = query.Where("admin_id = ?", adminID)
+	}
+	if targetUserID := c.Query("user_id"); targetUserID != "" {
+		query = query.Where("target_user_id = ?", targetUserID)
+	}
+
+	var impersonations []models.Impersonation
+	if err := query.Order("created_at DESC").Limit(100).Find(&impersonations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch impersonations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"impersonations": impersonations})
+}
+
+// GetImpersonationLogs lists every request made during one impersonation.
+func (h *ImpersonationHandler) GetImpersonationLogs(c *gin.Context) {
+	impersonationID := c.Param("id")
+
+	var impersonation models.Impersonation
+	if err := h.db.First(&impersonation, impersonationID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Impersonation not found"})
+		return
+	}
+
+	var logs []models.ImpersonationLog
+	if err := h.db.Where("impersonation_id = ?", impersonation.ImpersonationID).
+		Order("created_at ASC").
+		Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch impersonation logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"impersonation": impersonation,
+		"logs":          logs,
+	})
+}
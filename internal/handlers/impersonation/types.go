@@ -0,0 +1,15 @@
+package impersonation
+
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+type ImpersonationHandler struct {
+	db             *gorm.DB
+	impersonations *services.ImpersonationManager
+}
+
+func NewImpersonationHandler(db *gorm.DB) *ImpersonationHandler {
+	return &ImpersonationHandler{db: db, impersonations: services.NewImpersonationManager(db)}
+}
@@ -0,0 +1,100 @@
+package moderation
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+// GetModerationLogs lists moderation log entries, newest first, filtered by
+// actor_id, user_id (the target user), action and a from/to date range.
+func (h *ModerationHandler) GetModerationLogs(c *gin.Context) {
+	query := h.db.Model(&models.ModerationLog{})
+
+	if actorID := c.Query("actor_id"); actorID != "" {
+		query = query.Where("actor_id = ?", actorID)
+	}
+	if targetUserID := c.Query("user_id"); targetUserID != "" {
+		query = query.Where("target_user_id = ?", targetUserID)
+	}
+	if action := c.Query("action"); action != "" {
+		if !services.Contains(services.ModerationActions, action) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid action"})
+			return
+		}
+		query = query.Where("action = ?", action)
+	}
+
+	if from := c.Query("from"); from != "" {
+		fromTime, _, err := parseDate(from)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+			return
+		}
+		query = query.Where("created_at >= ?", fromTime)
+	}
+	if to := c.Query("to"); to != "" {
+		toTime, dateOnly, err := parseDate(to)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+			return
+		}
+		// A date without a time includes the whole day.
+		if dateOnly {
+			toTime = toTime.AddDate(0, 0, 1)
+			query = query.Where("created_at < ?", toTime)
+		} else {
+			query = query.Where("created_at <= ?", toTime)
+		}
+	}
+
+	page := c.DefaultQuery("page", "1")
+	perPage := c.DefaultQuery("per_page", "50")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil || pageInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	perPageInt, err := strconv.Atoi(perPage)
+	if err != nil || perPageInt < 1 || perPageInt > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid per_page number"})
+		return
+	}
+
+	// The filters are shared by the count and the page query.
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count moderation logs"})
+		return
+	}
+
+	logs := []models.ModerationLog{}
+	if err := query.Order("created_at DESC, moderation_log_id DESC").
+		Limit(perPageInt).
+		Offset((pageInt - 1) * perPageInt).
+		Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderation logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"logs": logs, "total": total})
+}
+
+// parseDate accepts RFC 3339 timestamps and plain dates, and reports which
+// one it got.
+func parseDate(value string) (time.Time, bool, error) {
+	if date, err := time.Parse(time.DateOnly, value); err == nil {
+		return date, true, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, value)
+	return timestamp, false, err
+}
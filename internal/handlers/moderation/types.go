@@ -0,0 +1,11 @@
+package moderation
+
+import "gorm.io/gorm"
+
+type ModerationHandler struct {
+	db *gorm.DB
+}
+
+func NewModerationHandler(db *gorm.DB) *ModerationHandler {
+	return &ModerationHandler{db: db}
+}
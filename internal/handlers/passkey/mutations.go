@@ -0,0 +1,131 @@
+package passkey
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+var errLastLoginMethod = errors.New("cannot remove the last login method")
+
+// BeginRegistration returns the options the frontend passes to
+// navigator.credentials.create.
+func (h *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	creation, err := h.passkeys.BeginRegistration(c, currentUser, services.PasskeyName(input.Name))
+	if errors.Is(err, services.ErrPasskeysNotConfigured) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Passkeys are not configured"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passkey registration"})
+		return
+	}
+
+	c.JSON(http.StatusOK, creation)
+}
+
+// FinishRegistration takes the credential returned by
+// navigator.credentials.create as the request body.
+func (h *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	passkey, err := h.passkeys.FinishRegistration(c, currentUser)
+	switch {
+	case errors.Is(err, services.ErrPasskeysNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Passkeys are not configured"})
+	case errors.Is(err, services.ErrInvalidPasskey):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Passkey registration failed or expired, please try again"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save passkey"})
+	default:
+		c.JSON(http.StatusCreated, gin.H{"passkey": passkey})
+	}
+}
+
+func (h *PasskeyHandler) DeletePasskey(c *gin.Context) {
+	passkeyID := c.Param("id")
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var passkey models.WebAuthnCredential
+		if err := tx.Where("web_authn_credential_id = ? AND user_id = ?", passkeyID, currentUser.UserID).First(&passkey).Error; err != nil {
+			return err
+		}
+
+		if currentUser.PasswordHash == "" {
+			var otherMethods, identities int64
+			if err := tx.Model(&models.WebAuthnCredential{}).
+				Where("user_id = ? AND web_authn_credential_id <> ?", currentUser.UserID, passkey.WebAuthnCredentialID).
+				Count(&otherMethods).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Model(&models.Identity{}).Where("user_id = ?", currentUser.UserID).Count(&identities).Error; err != nil {
+				return err
+			}
+
+			if otherMethods+identities == 0 {
+				return errLastLoginMethod
+			}
+		}
+
+		return tx.Delete(&passkey).Error
+	})
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passkey not found"})
+	case errors.Is(err, errLastLoginMethod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set a password or link a provider before removing the last login method"})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove passkey"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Passkey removed successfully"})
+	}
+}
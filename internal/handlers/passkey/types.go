@@ -0,0 +1,15 @@
+package passkey
+
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+type PasskeyHandler struct {
+	db       *gorm.DB
+	passkeys *services.PasskeyManager
+}
+
+func NewPasskeyHandler(db *gorm.DB, passkeys *services.PasskeyManager) *PasskeyHandler {
+	return &PasskeyHandler{db: db, passkeys: passkeys}
+}
@@ -0,0 +1,164 @@
+package premoderation
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/middleware"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+var errAlreadyReviewed = errors.New("post already reviewed")
+
+// ReviewPost approves a pending thread or comment, which makes it visible to
+// everyone, or rejects it, which removes it as a moderator.
+func (h *PremoderationHandler) ReviewPost(c *gin.Context) {
+	var input struct {
+		TargetType string `json:"target_type" binding:"required"`
+		TargetID   uint   `json:"target_id" binding:"required"`
+		Action     string `json:"action" binding:"required,oneof=approve reject"`
+		Reason     string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	var (
+		post         interface{}
+		before       interface{}
+		authorID     uint
+		categoryID   uint
+		deleteAction string
+	)
+
+	switch input.TargetType {
+	case services.ReportTargetThread:
+		var thread models.Thread
+		if err := h.db.Where("visibility = ? AND is_deleted = ?", services.VisibilityPending, false).
+			First(&thread, input.TargetID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pending thread not found"})
+			return
+		}
+		post, before, authorID, categoryID = &thread, thread, thread.UserID, thread.CategoryID
+		deleteAction = services.ModerationDeleteThread
+	case services.ReportTargetComment:
+		var comment models.Comment
+		if err := h.db.Where("visibility = ? AND is_deleted = ?", services.VisibilityPending, false).
+			First(&comment, input.TargetID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Pending comment not found"})
+			return
+		}
+
+		var thread models.Thread
+		if err := h.db.First(&thread, comment.ThreadID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Thread not found"})
+			return
+		}
+		post, before, authorID, categoryID = &comment, comment, comment.UserID, thread.CategoryID
+		deleteAction = services.ModerationDeleteComment
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target_type"})
+		return
+	}
+
+	if !middleware.HasCategoryPermission(c, h.db, services.PermissionPostApprove, categoryID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to review this " + input.TargetType})
+		return
+	}
+
+	updates := map[string]interface{}{"visibility": services.VisibilityVisible}
+	action := services.ModerationApprovePost
+	message := "Post approved"
+	if input.Action == "reject" {
+		updates = services.RemovalColumns(services.RemovedByModerator, currentUser.UserID, nil)
+		action = deleteAction
+		message = "Post rejected"
+	}
+
+	// The update only applies while the post is still pending, so two
+	// moderators reviewing it at once cannot both act on it.
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(post).Where("visibility = ? AND is_deleted = ?", services.VisibilityPending, false).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAlreadyReviewed
+		}
+
+		// Approved comments are counted in their thread's stats, which
+		// skipped them while they were pending.
+		if comment, isComment := post.(*models.Comment); isComment && input.Action == "approve" {
+			if err := services.AdjustCommentCount(tx, comment.ThreadID, 1); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.First(post).Error; err != nil {
+			return err
+		}
+
+		return services.RecordModeration(tx, currentUser, services.ModerationEntry{
+			Action:       action,
+			TargetType:   input.TargetType,
+			TargetID:     input.TargetID,
+			TargetUserID: authorID,
+			Reason:       strings.TrimSpace(input.Reason),
+			Before:       before,
+			After:        post,
+		})
+	})
+	if errors.Is(err, errAlreadyReviewed) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This " + input.TargetType + " has already been reviewed"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review " + input.TargetType})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message, input.TargetType: post})
+}
+
+// SetThresholds changes the reputation and account age below which new
+// threads and comments wait for approval. Zero turns a threshold off.
+func (h *PremoderationHandler) SetThresholds(c *gin.Context) {
+	var input struct {
+		MinReputation      *int `json:"min_reputation" binding:"required,min=0"`
+		MinAccountAgeHours *int `json:"min_account_age_hours" binding:"required,min=0"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	thresholds := services.PremoderationThresholds{
+		MinReputation:      *input.MinReputation,
+		MinAccountAgeHours: *input.MinAccountAgeHours,
+	}
+	if err := h.premoderation.SetThresholds(&thresholds); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pre-moderation thresholds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"thresholds": thresholds})
+}
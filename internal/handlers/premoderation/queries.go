@@ -0,0 +1,97 @@
+package premoderation
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/middleware"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+)
+
+// GetPendingPosts lists threads or comments awaiting approval, oldest first.
+// Category moderators only see their categories.
+func (h *PremoderationHandler) GetPendingPosts(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	var categoryIDs []uint
+	if !middleware.HasPermission(c, h.db, services.PermissionPostApprove) {
+		if !middleware.HasScope(c, services.ScopeModerate) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have the " + services.PermissionPostApprove + " permission"})
+			return
+		}
+
+		moderated, err := h.rbac.ModeratedCategories(currentUser.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderated categories"})
+			return
+		}
+		if len(moderated) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have the " + services.PermissionPostApprove + " permission"})
+			return
+		}
+		categoryIDs = moderated
+	}
+
+	targetType := c.DefaultQuery("target_type", services.ReportTargetThread)
+	if targetType != services.ReportTargetThread && targetType != services.ReportTargetComment {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target_type"})
+		return
+	}
+
+	page := c.DefaultQuery("page", "1")
+	perPage := c.DefaultQuery("per_page", "20")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil || pageInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	perPageInt, err := strconv.Atoi(perPage)
+	if err != nil || perPageInt < 1 || perPageInt > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid per_page number"})
+		return
+	}
+
+	offset := (pageInt - 1) * perPageInt
+
+	if targetType == services.ReportTargetThread {
+		threads, total, err := h.premoderation.PendingThreads(categoryIDs, perPageInt, offset)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending threads"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"threads": threads, "total": total})
+		return
+	}
+
+	comments, total, err := h.premoderation.PendingComments(categoryIDs, perPageInt, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pending comments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comments": comments, "total": total})
+}
+
+func (h *PremoderationHandler) GetThresholds(c *gin.Context) {
+	thresholds, err := h.premoderation.Thresholds()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pre-moderation thresholds"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"thresholds": thresholds})
+}
@@ -0,0 +1,20 @@
+package premoderation
+
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+type PremoderationHandler struct {
+	db            *gorm.DB
+	premoderation *services.PremoderationManager
+	rbac          *services.RBACManager
+}
+
+func NewPremoderationHandler(db *gorm.DB) *PremoderationHandler {
+	return &PremoderationHandler{
+		db:            db,
+		premoderation: services.NewPremoderationManager(db),
+		rbac:          services.NewRBACManager(db),
+	}
+}
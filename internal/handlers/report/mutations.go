@@ -0,0 +1,325 @@
+package report
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/middleware"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+// errResponseWritten rolls back a transaction whose helper has already
+// written the error response.
+var errResponseWritten = errors.New("response already written")
+
+func (h *ReportHandler) CreateReport(c *gin.Context) {
+	var input struct {
+		TargetType string `json:"target_type" binding:"required"`
+		TargetID   uint   `json:"target_id" binding:"required"`
+		Reason     string `json:"reason" binding:"required"`
+		Note       string `json:"note" binding:"max=1000"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !services.Contains(services.ReportReasons, input.Reason) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reason, expected one of " + strings.Join(services.ReportReasons, ", ")})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	report, err := h.reports.Create(currentUser, input.TargetType, input.TargetID, input.Reason, strings.TrimSpace(input.Note))
+	switch {
+	case errors.Is(err, services.ErrReportTargetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reported content not found"})
+		return
+	case errors.Is(err, services.ErrReportOwnContent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot report your own content"})
+		return
+	case errors.Is(err, services.ErrDuplicateReport):
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already reported this content"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create report"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Thank you, a moderator will review your report", "report": report})
+}
+
+// ResolveReports closes every open report about a thread or comment. The
+// action can dismiss them, which also publishes content held by automod,
+// delete the content, or suspend its author, and every reporter with a
+// verified email is told the outcome.
+func (h *ReportHandler) ResolveReports(c *gin.Context) {
+	var input struct {
+		TargetType    string `json:"target_type" binding:"required"`
+		TargetID      uint   `json:"target_id" binding:"required"`
+		Action        string `json:"action" binding:"required,oneof=dismiss delete_content suspend_author"`
+		Note          string `json:"note"`
+		DurationHours int    `json:"duration_hours" binding:"min=0"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	open, err := h.reports.OpenReports(input.TargetType, input.TargetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+	if len(open) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No open reports for this content"})
+		return
+	}
+
+	categoryID := open[0].CategoryID
+	authorID := open[0].TargetUserID
+	if !middleware.HasCategoryPermission(c, h.db, services.PermissionReportReview, categoryID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to resolve these reports"})
+		return
+	}
+
+	note := strings.TrimSpace(input.Note)
+	resolution := services.ReportDismissed
+	var resolved []models.Report
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		switch input.Action {
+		case "dismiss":
+			// Content held by automod is published once its reports are dismissed.
+			if err := services.NewAutomodManager(tx).Release(input.TargetType, input.TargetID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release held content"})
+				return errResponseWritten
+			}
+		case "delete_content":
+			if !h.deleteContent(c, tx, currentUser, input.TargetType, input.TargetID, categoryID, note) {
+				return errResponseWritten
+			}
+			resolution = services.ReportContentDeleted
+		case "suspend_author":
+			if !h.suspendAuthor(c, tx, currentUser, authorID, note, time.Duration(input.DurationHours)*time.Hour) {
+				return errResponseWritten
+			}
+			resolution = services.ReportAuthorSuspended
+		}
+
+		var err error
+		resolved, err = services.NewReportManager(tx).Resolve(input.TargetType, input.TargetID, resolution, note, currentUser)
+		if err != nil {
+			return err
+		}
+
+		return services.RecordModeration(tx, currentUser, services.ModerationEntry{
+			Action:       services.ModerationResolveReports,
+			TargetType:   input.TargetType,
+			TargetID:     input.TargetID,
+			TargetUserID: authorID,
+			Reason:       note,
+			Before:       gin.H{"status": services.ReportStatusOpen, "reports": len(open)},
+			After:        gin.H{"status": services.ReportStatusResolved, "resolution": resolution, "reports": len(resolved)},
+		})
+	})
+	if err != nil {
+		if !errors.Is(err, errResponseWritten) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve reports"})
+		}
+		return
+	}
+
+	h.notifyReporters(c, resolved)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reports resolved", "resolution": resolution, "resolved": len(resolved)})
+}
+
+// deleteContent removes the reported thread or comment as a moderator. Content
+// that is already removed is left as it is, so the original removal reason is
+// kept. It writes the error response itself and reports whether to continue.
+func (h *ReportHandler) deleteContent(c *gin.Context, tx *gorm.DB, moderator *models.User, targetType string, targetID, categoryID uint, reason string) bool {
+	removal := services.RemovalColumns(services.RemovedByModerator, moderator.UserID, nil)
+
+	if targetType == services.ReportTargetThread {
+		if !middleware.HasCategoryPermission(c, h.db, services.PermissionThreadDeleteAny, categoryID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to delete this thread"})
+			return false
+		}
+
+		var thread models.Thread
+		if err := tx.First(&thread, targetID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Thread not found"})
+			return false
+		}
+
+		before := thread
+		result := tx.Model(&thread).Where("is_deleted = ?", false).Updates(removal)
+		if result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete thread"})
+			return false
+		}
+		if result.RowsAffected == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Thread has already been deleted"})
+			return false
+		}
+
+		if err := tx.First(&thread, targetID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete thread"})
+			return false
+		}
+
+		if err := services.RecordModeration(tx, moderator, services.ModerationEntry{
+			Action:       services.ModerationDeleteThread,
+			TargetType:   services.ModerationTargetThread,
+			TargetID:     thread.ThreadID,
+			TargetUserID: thread.UserID,
+			Reason:       reason,
+			Before:       before,
+			After:        thread,
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record moderation action"})
+			return false
+		}
+		return true
+	}
+
+	if !middleware.HasCategoryPermission(c, h.db, services.PermissionCommentDeleteAny, categoryID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to delete this comment"})
+		return false
+	}
+
+	var comment models.Comment
+	if err := tx.First(&comment, targetID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return false
+	}
+
+	before := comment
+	result := tx.Model(&comment).Where("is_deleted = ?", false).Updates(removal)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return false
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Comment has already been deleted"})
+		return false
+	}
+
+	if err := tx.First(&comment, targetID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return false
+	}
+
+	if err := services.RecordModeration(tx, moderator, services.ModerationEntry{
+		Action:       services.ModerationDeleteComment,
+		TargetType:   services.ModerationTargetComment,
+		TargetID:     comment.CommentID,
+		TargetUserID: comment.UserID,
+		Reason:       reason,
+		Before:       before,
+		After:        comment,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record moderation action"})
+		return false
+	}
+	return true
+}
+
+// suspendAuthor suspends the author of the reported content. It writes the
+// error response itself and reports whether to continue.
+func (h *ReportHandler) suspendAuthor(c *gin.Context, tx *gorm.DB, moderator *models.User, authorID uint, reason string, duration time.Duration) bool {
+	if !middleware.HasPermission(c, h.db, services.PermissionUserBan) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have the " + services.PermissionUserBan + " permission"})
+		return false
+	}
+
+	var author models.User
+	if err := tx.First(&author, authorID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return false
+	}
+
+	outranks, err := h.rbac.Outranks(moderator, &author)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user roles"})
+		return false
+	}
+
+	if !outranks {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot suspend a user with the same or a higher role"})
+		return false
+	}
+
+	if reason == "" {
+		reason = "Reported content"
+	}
+
+	wasBanned := author.IsBanned
+	suspension, err := services.NewSuspensionManager(tx).Suspend(&author, moderator, reason, duration)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suspend user"})
+		return false
+	}
+
+	if err := services.RecordModeration(tx, moderator, services.ModerationEntry{
+		Action:       services.ModerationSuspendUser,
+		TargetType:   services.ModerationTargetUser,
+		TargetID:     author.UserID,
+		TargetUserID: author.UserID,
+		Reason:       reason,
+		Before:       gin.H{"is_banned": wasBanned},
+		After:        gin.H{"is_banned": true, "suspension": suspension},
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record moderation action"})
+		return false
+	}
+	return true
+}
+
+func (h *ReportHandler) notifyReporters(c *gin.Context, reports []models.Report) {
+	for i := range reports {
+		var reporter models.User
+		if err := h.db.First(&reporter, reports[i].ReporterID).Error; err != nil {
+			continue
+		}
+
+		if reporter.Email == nil || reporter.EmailVerifiedAt == nil {
+			continue
+		}
+
+		if err := services.SendReportResolvedEmail(c.Request.Context(), h.mailer, &reporter, *reporter.Email, &reports[i]); err != nil {
+			log.Printf("Error sending report outcome to user %d: %v", reporter.UserID, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package report
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/middleware"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+)
+
+// GetMyReports lists the reports of the current user and how they were
+// resolved, without naming the moderator.
+func (h *ReportHandler) GetMyReports(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	var reports []models.Report
+	if err := h.db.Where("reporter_id = ?", currentUser.UserID).Order("created_at DESC").Limit(100).Find(&reports).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
+		return
+	}
+
+	response := make([]gin.H, len(reports))
+	for i, report := range reports {
+		response[i] = gin.H{
+			"report_id":   report.ReportID,
+			"target_type": report.TargetType,
+			"target_id":   report.TargetID,
+			"reason":      report.Reason,
+			"note":        report.Note,
+			"status":      report.Status,
+			"resolution":  report.Resolution,
+			"resolved_at": report.ResolvedAt,
+			"created_at":  report.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": response})
+}
+
+// GetReportQueue lists open reports grouped by the reported thread or comment,
+// most reported first. Category moderators only see their categories.
+func (h *ReportHandler) GetReportQueue(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	var categoryIDs []uint
+	if !middleware.HasPermission(c, h.db, services.PermissionReportReview) {
+		if !middleware.HasScope(c, services.ScopeModerate) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have the " + services.PermissionReportReview + " permission"})
+			return
+		}
+
+		moderated, err := h.rbac.ModeratedCategories(currentUser.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderated categories"})
+			return
+		}
+		if len(moderated) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have the " + services.PermissionReportReview + " permission"})
+			return
+		}
+		categoryIDs = moderated
+	}
+
+	targetType := c.Query("target_type")
+	if targetType != "" && targetType != services.ReportTargetThread && targetType != services.ReportTargetComment {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target_type"})
+		return
+	}
+
+	page := c.DefaultQuery("page", "1")
+	perPage := c.DefaultQuery("per_page", "20")
+
+	pageInt, err := strconv.Atoi(page)
+	if err != nil || pageInt < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	perPageInt, err := strconv.Atoi(perPage)
+	if err != nil || perPageInt < 1 || perPageInt > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid per_page number"})
+		return
+	}
+
+	groups, total, err := h.reports.Queue(categoryIDs, targetType, perPageInt, (pageInt-1)*perPageInt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch report queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"queue": groups, "total": total})
+}
@@ -0,0 +1,22 @@
+package report
+
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+type ReportHandler struct {
+	db      *gorm.DB
+	reports *services.ReportManager
+	rbac    *services.RBACManager
+	mailer  services.Mailer
+}
+
+func NewReportHandler(db *gorm.DB) *ReportHandler {
+	return &ReportHandler{
+		db:      db,
+		reports: services.NewReportManager(db),
+		rbac:    services.NewRBACManager(db),
+		mailer:  services.NewMailerFromEnv(),
+	}
+}
@@ -0,0 +1,128 @@
+package role
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+// SetUserRoles replaces the roles of a user. Staff can only change users below
+// them and only hand out roles below their own level.
+func (h *RoleHandler) SetUserRoles(c *gin.Context) {
+	userID := c.Param("id")
+
+	var input struct {
+		Roles  []string `json:"roles" binding:"required"`
+		Reason string   `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	var targetUser models.User
+	if err := h.db.First(&targetUser, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	outranks, err := h.rbac.Outranks(currentUser, &targetUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user roles"})
+		return
+	}
+
+	if !outranks {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot change the roles of a user with the same or a higher role"})
+		return
+	}
+
+	roles, err := h.rbac.AllRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+
+	currentLevel, err := h.rbac.RoleLevel(currentUser.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user roles"})
+		return
+	}
+
+	for _, name := range input.Roles {
+		for _, role := range roles {
+			if role.Name == name && role.Level >= currentLevel {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You cannot assign the " + name + " role"})
+				return
+			}
+		}
+	}
+
+	previous, err := h.rbac.RoleNames(targetUser.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user roles"})
+		return
+	}
+
+	var assigned []models.Role
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		rbac := services.NewRBACManager(tx)
+		if err := rbac.SetRoles(&targetUser, input.Roles); err != nil {
+			return err
+		}
+
+		var err error
+		assigned, err = rbac.Roles(targetUser.UserID)
+		if err != nil {
+			return err
+		}
+
+		assignedNames := make([]string, len(assigned))
+		for i, role := range assigned {
+			assignedNames[i] = role.Name
+		}
+
+		return services.RecordModeration(tx, currentUser, services.ModerationEntry{
+			Action:       services.ModerationSetRoles,
+			TargetType:   services.ModerationTargetUser,
+			TargetID:     targetUser.UserID,
+			TargetUserID: targetUser.UserID,
+			Reason:       strings.TrimSpace(input.Reason),
+			Before:       gin.H{"roles": previous},
+			After:        gin.H{"roles": assignedNames},
+		})
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownRole) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User roles updated successfully",
+		"user_id": targetUser.UserID,
+		"role_id": targetUser.RoleID,
+		"roles":   assigned,
+	})
+}
@@ -0,0 +1,69 @@
+package role
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+)
+
+// GetRoles lists every role with the permissions it grants.
+func (h *RoleHandler) GetRoles(c *gin.Context) {
+	roles, err := h.rbac.AllRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+
+	rolePermissions, err := h.rbac.RolePermissions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch role permissions"})
+		return
+	}
+
+	response := make([]gin.H, len(roles))
+	for i, role := range roles {
+		permissions := rolePermissions[role.Name]
+		if permissions == nil {
+			permissions = []string{}
+		}
+
+		response[i] = gin.H{
+			"role_id":     role.RoleID,
+			"name":        role.Name,
+			"description": role.Description,
+			"level":       role.Level,
+			"permissions": permissions,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": response})
+}
+
+func (h *RoleHandler) GetUserRoles(c *gin.Context) {
+	userID := c.Param("id")
+
+	var targetUser models.User
+	if err := h.db.First(&targetUser, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	roles, err := h.rbac.Roles(targetUser.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user roles"})
+		return
+	}
+
+	permissions, err := h.rbac.Permissions(targetUser.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user permissions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":     targetUser.UserID,
+		"roles":       roles,
+		"permissions": permissions,
+	})
+}
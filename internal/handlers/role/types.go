@@ -0,0 +1,15 @@
+package role
+
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+type RoleHandler struct {
+	db   *gorm.DB
+	rbac *services.RBACManager
+}
+
+func NewRoleHandler(db *gorm.DB) *RoleHandler {
+	return &RoleHandler{db: db, rbac: services.NewRBACManager(db)}
+}
@@ -0,0 +1,45 @@
+package session
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+)
+
+func (h *SessionHandler) GetActiveSessions(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	sessionID, _ := c.Get("session_id")
+	currentSessionID, _ := sessionID.(uint)
+
+	sessions, err := h.sessions.ActiveSessions(currentUser.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	result := []gin.H{}
+	for _, session := range sessions {
+		result = append(result, gin.H{
+			"session_id":   session.SessionID,
+			"created_at":   session.CreatedAt,
+			"last_used_at": session.LastUsedAt,
+			"user_agent":   session.UserAgent,
+			"ip_address":   session.IPAddress,
+			"is_current":   session.SessionID == currentSessionID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sessions": result})
+}
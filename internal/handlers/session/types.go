@@ -0,0 +1,15 @@
+package session
+
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+type SessionHandler struct {
+	db       *gorm.DB
+	sessions *services.SessionManager
+}
+
+func NewSessionHandler(db *gorm.DB) *SessionHandler {
+	return &SessionHandler{db: db, sessions: services.NewSessionManager(db)}
+}
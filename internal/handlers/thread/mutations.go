@@ -1,12 +1,19 @@
 package thread
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/middleware"
 	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
 )
 
+var errAlreadyDeleted = errors.New("thread already deleted")
+
 func (h *ThreadHandler) CreateThread(c *gin.Context) {
 	var input struct {
 		Title      string   `json:"title" binding:"required"`
@@ -40,7 +47,30 @@ func (h *ThreadHandler) CreateThread(c *gin.Context) {
 		Tags:       input.Tags,
 	}
 
-	if err := h.db.Create(&thread).Error; err != nil {
+	post := automodPost(currentUser, &thread)
+	verdict, ok := h.checkAutomod(c, post)
+	if !ok {
+		return
+	}
+	if verdict.Action == services.AutomodHold {
+		thread.Visibility = services.VisibilityHeld
+	} else {
+		pending, err := h.premoderation.IsRequired(currentUser)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check thread"})
+			return
+		}
+		if pending {
+			thread.Visibility = services.VisibilityPending
+		}
+	}
+
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&thread).Error; err != nil {
+			return err
+		}
+		return h.automod.Record(tx, verdict, post, thread.ThreadID)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create thread"})
 		return
 	}
@@ -94,7 +124,30 @@ func (h *ThreadHandler) UpdateThread(c *gin.Context) {
 		thread.Tags = input.Tags
 	}
 
-	if err := h.db.Save(&thread).Error; err != nil {
+	post := automodPost(currentUser, &thread)
+	verdict, ok := h.checkAutomod(c, post)
+	if !ok {
+		return
+	}
+
+	// Hold decides the visibility, so saving the edit cannot undo an approval
+	// or release made since the thread was loaded.
+	if err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("visibility").Save(&thread).Error; err != nil {
+			return err
+		}
+
+		if verdict.Action == services.AutomodHold {
+			if err := h.automod.Hold(tx, services.ReportTargetThread, thread.ThreadID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Select("visibility").First(&thread, thread.ThreadID).Error; err != nil {
+			return err
+		}
+		return h.automod.Record(tx, verdict, post, thread.ThreadID)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update thread"})
 		return
 	}
@@ -123,16 +176,85 @@ func (h *ThreadHandler) DeleteThread(c *gin.Context) {
 		return
 	}
 
-	if thread.UserID != currentUser.UserID && currentUser.RoleID <= 0 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to delete this thread"})
-		return
+	reason := services.RemovedByAuthor
+	if thread.UserID != currentUser.UserID {
+		if !middleware.HasCategoryPermission(c, h.db, services.PermissionThreadDeleteAny, thread.CategoryID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to delete this thread"})
+			return
+		}
+		reason = services.RemovedByModerator
 	}
 
-	thread.IsDeleted = true
-	if err := h.db.Save(&thread).Error; err != nil {
+	// Deleting again would overwrite who removed the thread and why, which
+	// decides whether lifting a suspension restores it.
+	before := thread
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&thread).Where("is_deleted = ?", false).Updates(services.RemovalColumns(reason, currentUser.UserID, nil))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAlreadyDeleted
+		}
+
+		if reason != services.RemovedByModerator {
+			return nil
+		}
+
+		if err := tx.First(&thread, thread.ThreadID).Error; err != nil {
+			return err
+		}
+
+		return services.RecordModeration(tx, currentUser, services.ModerationEntry{
+			Action:       services.ModerationDeleteThread,
+			TargetType:   services.ModerationTargetThread,
+			TargetID:     thread.ThreadID,
+			TargetUserID: thread.UserID,
+			Reason:       strings.TrimSpace(c.Query("reason")),
+			Before:       before,
+			After:        thread,
+		})
+	})
+	if errors.Is(err, errAlreadyDeleted) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Thread has already been deleted"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete thread"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Thread deleted"})
 }
+
+func automodPost(author *models.User, thread *models.Thread) services.AutomodPost {
+	return services.AutomodPost{
+		TargetType:       services.ReportTargetThread,
+		UserID:           author.UserID,
+		CategoryID:       thread.CategoryID,
+		Text:             thread.Title + "\n\n" + thread.Content,
+		AccountCreatedAt: &author.CreatedAt,
+	}
+}
+
+// checkAutomod runs the automod rules against a thread about to be saved and
+// records rejections. It writes the error response itself and reports whether
+// to continue.
+func (h *ThreadHandler) checkAutomod(c *gin.Context, post services.AutomodPost) (*services.AutomodVerdict, bool) {
+	verdict, err := h.automod.Check(post)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check thread"})
+		return nil, false
+	}
+
+	if verdict.Action == services.AutomodReject {
+		if err := h.automod.Record(h.db, verdict, post, 0); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check thread"})
+			return nil, false
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Thread rejected", "reasons": verdict.Messages()})
+		return nil, false
+	}
+
+	return verdict, true
+}
@@ -24,6 +24,16 @@ func (h *ThreadHandler) GetThread(c *gin.Context) {
 		return
 	}
 
+	// Held and pending threads are only shown to their author.
+	var viewer *models.User
+	if user, exists := c.Get("user"); exists {
+		viewer, _ = user.(*models.User)
+	}
+	if thread.Visibility != services.VisibilityVisible && (viewer == nil || viewer.UserID != thread.UserID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thread not found"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"thread": thread})
 }
 
@@ -64,6 +74,11 @@ func (h *ThreadHandler) GetFollowedThreads(c *gin.Context) {
 		return
 	}
 
+	var viewer *models.User
+	if user, exists := c.Get("user"); exists {
+		viewer, _ = user.(*models.User)
+	}
+
 	var threadIds []uint
 	for _, interaction := range interactions {
 		threadIds = append(threadIds, interaction.ThreadID)
@@ -73,6 +88,7 @@ func (h *ThreadHandler) GetFollowedThreads(c *gin.Context) {
 		query := h.db.Model(&models.Thread{}).
 			Where("thread_id IN ?", threadIds).
 			Where("is_deleted = ?", showDeleted).
+			Scopes(services.VisibleTo(viewer)).
 			Limit(perPageInt).
 			Offset(offset)
 
@@ -122,9 +138,15 @@ func (h *ThreadHandler) GetAllThreadsByCategory(c *gin.Context) {
 
 	offset := (pageInt - 1) * perPageInt
 
+	var viewer *models.User
+	if user, exists := c.Get("user"); exists {
+		viewer, _ = user.(*models.User)
+	}
+
 	query := h.db.Model(&models.Thread{}).
 		Where("category_id = ?", categoryID).
 		Where("is_deleted = ?", showDeleted).
+		Scopes(services.VisibleTo(viewer)).
 		Limit(perPageInt).
 		Offset(offset)
 
@@ -1,11 +1,20 @@
 package thread
 
-import "gorm.io/gorm"
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
 
 type ThreadHandler struct {
-	db *gorm.DB
+	db            *gorm.DB
+	automod       *services.AutomodManager
+	premoderation *services.PremoderationManager
 }
 
 func NewThreadHandler(db *gorm.DB) *ThreadHandler {
-	return &ThreadHandler{db: db}
+	return &ThreadHandler{
+		db:            db,
+		automod:       services.NewAutomodManager(db),
+		premoderation: services.NewPremoderationManager(db),
+	}
 }
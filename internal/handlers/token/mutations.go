@@ -0,0 +1,134 @@
+package token
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+)
+
+const (
+	defaultTokenLifetimeDays = 90
+	maxTokenLifetimeDays     = 365
+)
+
+func (h *TokenHandler) CreateToken(c *gin.Context) {
+	var input struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		ExpiresInDays *int     `json:"expires_in_days"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(input.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required"})
+		return
+	}
+
+	for _, scope := range input.Scopes {
+		if !services.Contains(services.APITokenScopes, scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope: " + scope})
+			return
+		}
+	}
+
+	// Moderation routes also pass the read or write check every API token
+	// request goes through, so a moderate token is useless without both.
+	if services.Contains(input.Scopes, services.ScopeModerate) &&
+		(!services.Contains(input.Scopes, services.ScopeRead) || !services.Contains(input.Scopes, services.ScopeWrite)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The moderate scope requires the read and write scopes"})
+		return
+	}
+
+	expiresInDays := defaultTokenLifetimeDays
+	if input.ExpiresInDays != nil {
+		expiresInDays = *input.ExpiresInDays
+	}
+	if expiresInDays < 1 || expiresInDays > maxTokenLifetimeDays {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be between 1 and 365"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	if services.Contains(input.Scopes, services.ScopeModerate) {
+		isStaff, err := h.rbac.IsStaff(currentUser)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user roles"})
+			return
+		}
+		if !isStaff {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only moderators and admins can create tokens with the moderate scope"})
+			return
+		}
+	}
+
+	secret, displayPrefix, hash, err := services.GenerateAPIToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API token"})
+		return
+	}
+
+	expiresAt := time.Now().AddDate(0, 0, expiresInDays)
+	apiToken := models.APIToken{
+		UserID:      currentUser.UserID,
+		Name:        input.Name,
+		TokenPrefix: displayPrefix,
+		TokenHash:   hash,
+		Scopes:      input.Scopes,
+		ExpiresAt:   &expiresAt,
+	}
+
+	if err := h.db.Create(&apiToken).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API token"})
+		return
+	}
+
+	// The secret is only ever returned here, the database keeps its digest.
+	c.JSON(http.StatusCreated, gin.H{"token": apiToken, "secret": secret})
+}
+
+func (h *TokenHandler) RevokeToken(c *gin.Context) {
+	tokenID := c.Param("id")
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	var apiToken models.APIToken
+	if err := h.db.First(&apiToken, tokenID).Error; err != nil || apiToken.UserID != currentUser.UserID || apiToken.RevokedAt != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+		return
+	}
+
+	if err := h.db.Model(&apiToken).Update("revoked_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+}
@@ -0,0 +1,15 @@
+package token
+
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+type TokenHandler struct {
+	db   *gorm.DB
+	rbac *services.RBACManager
+}
+
+func NewTokenHandler(db *gorm.DB) *TokenHandler {
+	return &TokenHandler{db: db, rbac: services.NewRBACManager(db)}
+}
@@ -0,0 +1,15 @@
+package twofactor
+
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+type TwoFactorHandler struct {
+	db        *gorm.DB
+	twoFactor *services.TwoFactorManager
+}
+
+func NewTwoFactorHandler(db *gorm.DB) *TwoFactorHandler {
+	return &TwoFactorHandler{db: db, twoFactor: services.NewTwoFactorManager(db)}
+}
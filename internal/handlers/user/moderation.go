@@ -1,15 +1,32 @@
 package user
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
 )
 
+// ToggleBanUser suspends the user until further notice, or lifts their
+// suspension. Use SuspendUser for suspensions that end on their own.
 func (h *UserHandler) ToggleBanUser(c *gin.Context) {
 	userID := c.Param("id")
 
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	var userToBan models.User
 	if err := h.db.First(&userToBan, userID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
@@ -28,50 +45,61 @@ func (h *UserHandler) ToggleBanUser(c *gin.Context) {
 		return
 	}
 
-	if currentUserData.RoleID <= 0 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to ban users"})
+	outranks, err := h.rbac.Outranks(currentUserData, &userToBan)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user roles"})
 		return
 	}
 
-	if currentUserData.RoleID > 1 && userToBan.RoleID > 1 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot ban another admin"})
+	if !outranks {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot ban a user with the same or a higher role"})
 		return
 	}
 
-	if currentUserData.RoleID == 1 && userToBan.RoleID == 1 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Moderators cannot ban other moderators"})
-		return
-	}
-
-	userToBan.IsBanned = !userToBan.IsBanned
-	if err := h.db.Save(&userToBan).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ban status"})
+	suspension, err := h.suspensions.Active(&userToBan)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check suspension"})
 		return
 	}
 
-	if userToBan.IsBanned {
-		if err := h.db.Model(&models.Comment{}).Where("user_id = ?", userID).Update("is_deleted", true).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user comments"})
+	reason := strings.TrimSpace(input.Reason)
+	if suspension != nil {
+		restored, err := h.lift(&userToBan, currentUserData, reason)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ban status"})
 			return
 		}
 
-		if err := h.db.Model(&models.Thread{}).Where("user_id = ?", userID).Update("is_deleted", true).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user threads"})
-			return
-		}
+		c.JSON(http.StatusOK, gin.H{"message": "Successfully unbanned the user", "restored": restored})
+		return
+	}
+
+	if reason == "" {
+		reason = "No reason given"
 	}
 
-	if userToBan.IsBanned {
-		c.JSON(http.StatusOK, gin.H{"message": "Successfully banned the user"})
+	if _, err := h.suspend(&userToBan, currentUserData, reason, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ban status"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Successfully unbanned the user"})
+	c.JSON(http.StatusOK, gin.H{"message": "Successfully banned the user"})
 }
 
 func (h *UserHandler) ToggleAssignModerator(c *gin.Context) {
 	userID := c.Param("id")
 
+	var input struct {
+		Reason string `json:"reason"`
+	}
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	var userToAssign models.User
 	if err := h.db.First(&userToAssign, userID).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
@@ -90,31 +118,160 @@ func (h *UserHandler) ToggleAssignModerator(c *gin.Context) {
 		return
 	}
 
-	if currentUserData.RoleID <= 1 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can assign moderators"})
+	outranks, err := h.rbac.Outranks(currentUserData, &userToAssign)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user roles"})
 		return
 	}
 
-	if userToAssign.RoleID > 1 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot assign moderator to another admin"})
+	if !outranks {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot change the role of a user with the same or a higher role"})
 		return
 	}
 
-	if userToAssign.RoleID == 1 {
-		userToAssign.RoleID = 0
-	} else {
-		userToAssign.RoleID = 1
+	roles, err := h.rbac.RoleNames(userToAssign.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user roles"})
+		return
 	}
 
-	if err := h.db.Save(&userToAssign).Error; err != nil {
+	isModerator := services.Contains(roles, services.RoleModerator)
+	reason := strings.TrimSpace(input.Reason)
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		rbac := services.NewRBACManager(tx)
+		action := services.ModerationAssignModerator
+		var err error
+		if isModerator {
+			action = services.ModerationRemoveModerator
+			err = rbac.RemoveRole(&userToAssign, services.RoleModerator)
+		} else {
+			err = rbac.AddRole(&userToAssign, services.RoleModerator)
+		}
+		if err != nil {
+			return err
+		}
+
+		return recordRoleChange(tx, currentUserData, &userToAssign, action, reason, roles)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
 		return
 	}
 
-	if userToAssign.RoleID == 1 {
+	if !isModerator {
 		c.JSON(http.StatusOK, gin.H{"message": "Successfully assigned user as moderator"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Successfully remove user from moderators"})
 }
+
+// SetModeratedCategories replaces the categories a user moderates. Category
+// moderators can only moderate threads and comments in those categories.
+func (h *UserHandler) SetModeratedCategories(c *gin.Context) {
+	userID := c.Param("id")
+
+	var input struct {
+		CategoryIDs []uint `json:"category_ids" binding:"required"`
+		Reason      string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var userToAssign models.User
+	if err := h.db.First(&userToAssign, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	currentUser, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUserData, ok := currentUser.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	outranks, err := h.rbac.Outranks(currentUserData, &userToAssign)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user roles"})
+		return
+	}
+
+	if !outranks {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot change the role of a user with the same or a higher role"})
+		return
+	}
+
+	previous, err := h.rbac.ModeratedCategories(userToAssign.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderated categories"})
+		return
+	}
+
+	var categoryIDs []uint
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		rbac := services.NewRBACManager(tx)
+		if err := rbac.SetModeratedCategories(&userToAssign, input.CategoryIDs, currentUserData.UserID); err != nil {
+			return err
+		}
+
+		var err error
+		categoryIDs, err = rbac.ModeratedCategories(userToAssign.UserID)
+		if err != nil {
+			return err
+		}
+
+		return services.RecordModeration(tx, currentUserData, services.ModerationEntry{
+			Action:       services.ModerationSetCategories,
+			TargetType:   services.ModerationTargetUser,
+			TargetID:     userToAssign.UserID,
+			TargetUserID: userToAssign.UserID,
+			Reason:       strings.TrimSpace(input.Reason),
+			Before:       gin.H{"moderated_categories": previous},
+			After:        gin.H{"moderated_categories": categoryIDs},
+		})
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownCategory) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown category"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update moderated categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "Successfully updated moderated categories",
+		"user_id":              userToAssign.UserID,
+		"role_id":              userToAssign.RoleID,
+		"moderated_categories": categoryIDs,
+	})
+}
+
+// recordRoleChange logs a change of the roles of a user, reading the roles
+// they hold now as the after snapshot.
+func recordRoleChange(tx *gorm.DB, actor, targetUser *models.User, action, reason string, previous []string) error {
+	roles, err := services.NewRBACManager(tx).RoleNames(targetUser.UserID)
+	if err != nil {
+		return err
+	}
+
+	return services.RecordModeration(tx, actor, services.ModerationEntry{
+		Action:       action,
+		TargetType:   services.ModerationTargetUser,
+		TargetID:     targetUser.UserID,
+		TargetUserID: targetUser.UserID,
+		Reason:       reason,
+		Before:       gin.H{"roles": previous},
+		After:        gin.H{"roles": roles},
+	})
+}
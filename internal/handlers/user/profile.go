@@ -2,11 +2,11 @@ package user
 
 import (
 	"net/http"
-	"regexp"
+	"net/mail"
 
 	"github.com/gin-gonic/gin"
 	"github.com/oadultradeepfield/olympliance-server/internal/models"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
 )
 
 func (h *UserHandler) ChangeUsername(c *gin.Context) {
@@ -20,8 +20,7 @@ func (h *UserHandler) ChangeUsername(c *gin.Context) {
 		return
 	}
 
-	isValidUsername := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString
-	if !isValidUsername(input.NewUsername) {
+	if !services.IsValidUsername(input.NewUsername) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Username can only contain letters, numbers, underscores, and dashes"})
 		return
 	}
@@ -50,6 +49,7 @@ func (h *UserHandler) ChangeUsername(c *gin.Context) {
 	}
 
 	currentUser.Username = input.NewUsername
+	currentUser.NeedsOnboarding = false
 	if err := h.db.Save(currentUser).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update username"})
 		return
@@ -58,6 +58,138 @@ func (h *UserHandler) ChangeUsername(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Username updated successfully"})
 }
 
+// CompleteOnboarding confirms the generated username of a new provider
+// account, or replaces it with one the user picked.
+func (h *UserHandler) CompleteOnboarding(c *gin.Context) {
+	var input struct {
+		Username string `json:"username" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !services.IsValidUsername(input.Username) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username can only contain letters, numbers, underscores, and dashes"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	if !currentUser.NeedsOnboarding {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Onboarding already completed"})
+		return
+	}
+
+	if input.Username != currentUser.Username {
+		var existingUser models.User
+		if err := h.db.Where("username = ?", input.Username).First(&existingUser).Error; err == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Username already taken"})
+			return
+		}
+	}
+
+	currentUser.Username = input.Username
+	currentUser.NeedsOnboarding = false
+	if err := h.db.Save(currentUser).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete onboarding"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Onboarding completed successfully", "username": currentUser.Username})
+}
+
+func (h *UserHandler) ChangeEmail(c *gin.Context) {
+	var input struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := services.NormalizeEmail(input.Email)
+	if _, err := mail.ParseAddress(email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	var existingUser models.User
+	if err := h.db.Where("email = ?", email).First(&existingUser).Error; err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already in use"})
+		return
+	}
+
+	currentUser.Email = &email
+	currentUser.EmailVerifiedAt = nil
+	if err := h.db.Save(currentUser).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update email"})
+		return
+	}
+
+	if err := services.SendVerificationEmail(c.Request.Context(), h.mailer, h.emailTokens, currentUser, email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Email updated but the verification email could not be sent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email updated, please check your inbox to verify it"})
+}
+
+func (h *UserHandler) ResendVerificationEmail(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	if currentUser.Email == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No email address set"})
+		return
+	}
+
+	if currentUser.EmailVerifiedAt != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already verified"})
+		return
+	}
+
+	if err := services.SendVerificationEmail(c.Request.Context(), h.mailer, h.emailTokens, currentUser, *currentUser.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send verification email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
 func (h *UserHandler) ChangePassword(c *gin.Context) {
 	var input struct {
 		CurrentPassword string `json:"current_password" binding:"required"`
@@ -70,11 +202,6 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	if len(input.NewPassword) < 8 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters long"})
-		return
-	}
-
 	user, exists := c.Get("user")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
@@ -87,7 +214,18 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(currentUser.PasswordHash), []byte(input.CurrentPassword)); err != nil {
+	if currentUser.PasswordHash == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Account has no password yet, set one instead"})
+		return
+	}
+
+	valid, _, err := h.hasher.Verify(input.CurrentPassword, currentUser.PasswordHash)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify current password"})
+		return
+	}
+
+	if !valid {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
 		return
 	}
@@ -97,21 +235,95 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err := services.CheckPassword(input.NewPassword, services.UserIdentifiers(currentUser)...); err != nil {
+		services.RespondPasswordError(c, err)
+		return
+	}
+
+	hashedPassword, err := h.hasher.Hash(input.NewPassword)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash new password"})
 		return
 	}
 
-	currentUser.PasswordHash = string(hashedPassword)
+	currentUser.PasswordHash = hashedPassword
 	if err := h.db.Save(currentUser).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
 	}
 
+	sessionID, _ := c.Get("session_id")
+	currentSessionID, _ := sessionID.(uint)
+	if err := h.sessions.RevokeUserSessions(currentUser.UserID, currentSessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke other sessions"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
 }
 
+// SetPassword lets an account created through a sign-in provider add a
+// password as a second login method.
+func (h *UserHandler) SetPassword(c *gin.Context) {
+	var input struct {
+		NewPassword     string `json:"new_password" binding:"required"`
+		ConfirmPassword string `json:"confirm_password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.NewPassword != input.ConfirmPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password and confirmation do not match"})
+		return
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	if currentUser.PasswordHash != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password already set, use change password instead"})
+		return
+	}
+
+	if err := services.CheckPassword(input.NewPassword, services.UserIdentifiers(currentUser)...); err != nil {
+		services.RespondPasswordError(c, err)
+		return
+	}
+
+	hashedPassword, err := h.hasher.Hash(input.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash new password"})
+		return
+	}
+
+	result := h.db.Model(&models.User{}).
+		Where("user_id = ? AND password_hash = ''", currentUser.UserID).
+		Update("password_hash", hashedPassword)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set password"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password already set, use change password instead"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password set successfully"})
+}
+
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	user, exists := c.Get("user")
 	if !exists {
@@ -131,5 +343,12 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
+	if err := h.sessions.RevokeUserSessions(currentUser.UserID, 0); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	services.ClearAuthCookies(c)
+
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
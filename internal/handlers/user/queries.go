@@ -53,11 +53,6 @@ func (h *UserHandler) GetUserIDbyUsername(c *gin.Context) {
 		return
 	}
 
-	if currentUserData.RoleID <= 0 {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to view user IDs"})
-		return
-	}
-
 	username := c.Param("username")
 	if username == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Username is required"})
@@ -70,7 +65,13 @@ func (h *UserHandler) GetUserIDbyUsername(c *gin.Context) {
 		return
 	}
 
-	if targetUser.RoleID >= currentUserData.RoleID {
+	outranks, err := h.rbac.Outranks(currentUserData, &targetUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user roles"})
+		return
+	}
+
+	if !outranks {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot view this user's ID"})
 		return
 	}
@@ -91,13 +92,67 @@ func (h *UserHandler) GetCurrentUserInformation(c *gin.Context) {
 		return
 	}
 
+	roles, err := h.rbac.Roles(currentUser.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user roles"})
+		return
+	}
+
+	roleNames := make([]string, len(roles))
+	for i, role := range roles {
+		roleNames[i] = role.Name
+	}
+
+	permissions, err := h.rbac.Permissions(currentUser.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user permissions"})
+		return
+	}
+
+	moderatedCategories, err := h.rbac.ModeratedCategories(currentUser.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch moderated categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":              currentUser.UserID,
+		"username":             currentUser.Username,
+		"role_id":              currentUser.RoleID,
+		"roles":                roleNames,
+		"permissions":          permissions,
+		"moderated_categories": moderatedCategories,
+		"reputation":           currentUser.Reputation,
+		"is_banned":            currentUser.IsBanned,
+		"is_deleted":           currentUser.IsDeleted,
+		"needs_onboarding":     currentUser.NeedsOnboarding,
+		"email":                currentUser.Email,
+		"email_verified":       currentUser.EmailVerifiedAt != nil,
+	})
+}
+
+func (h *UserHandler) GetLinkedIdentities(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	var identities []models.Identity
+	if err := h.db.Where("user_id = ?", currentUser.UserID).Order("created_at ASC").Find(&identities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch linked identities"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"user_id":    currentUser.UserID,
-		"username":   currentUser.Username,
-		"role_id":    currentUser.RoleID,
-		"reputation": currentUser.Reputation,
-		"is_banned":  currentUser.IsBanned,
-		"is_deleted": currentUser.IsDeleted,
+		"identities":   identities,
+		"has_password": currentUser.PasswordHash != "",
 	})
 }
 
@@ -0,0 +1,232 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+// SuspendUser suspends a user with a reason, for duration_hours or, without
+// it, until the suspension is lifted.
+func (h *UserHandler) SuspendUser(c *gin.Context) {
+	userID := c.Param("id")
+
+	var input struct {
+		Reason        string `json:"reason" binding:"required"`
+		DurationHours int    `json:"duration_hours" binding:"min=0"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	var targetUser models.User
+	if err := h.db.First(&targetUser, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	currentUser, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUserData, ok := currentUser.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	outranks, err := h.rbac.Outranks(currentUserData, &targetUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user roles"})
+		return
+	}
+
+	if !outranks {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot suspend a user with the same or a higher role"})
+		return
+	}
+
+	suspension, err := h.suspend(&targetUser, currentUserData, reason, time.Duration(input.DurationHours)*time.Hour)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to suspend user"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "Successfully suspended the user", "suspension": suspension})
+}
+
+func (h *UserHandler) LiftSuspension(c *gin.Context) {
+	userID := c.Param("id")
+
+	var targetUser models.User
+	if err := h.db.First(&targetUser, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	currentUser, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	currentUserData, ok := currentUser.(*models.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+		return
+	}
+
+	outranks, err := h.rbac.Outranks(currentUserData, &targetUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check user roles"})
+		return
+	}
+
+	if !outranks {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot lift the suspension of a user with the same or a higher role"})
+		return
+	}
+
+	restored, err := h.lift(&targetUser, currentUserData, strings.TrimSpace(c.Query("reason")))
+	if err != nil {
+		if errors.Is(err, services.ErrNotSuspended) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "User is not suspended"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lift suspension"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Successfully lifted the suspension", "restored": restored})
+}
+
+// GetRestorableContent previews the threads and comments that lifting the
+// suspension of a user would restore.
+func (h *UserHandler) GetRestorableContent(c *gin.Context) {
+	userID := c.Param("id")
+
+	var targetUser models.User
+	if err := h.db.First(&targetUser, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	content, err := h.suspensions.RestorableContent(targetUser.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch removed content"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":  targetUser.UserID,
+		"threads":  content.Threads,
+		"comments": content.Comments,
+	})
+}
+
+// GetSuspensions lists every suspension of a user, newest first.
+func (h *UserHandler) GetSuspensions(c *gin.Context) {
+	userID := c.Param("id")
+
+	var targetUser models.User
+	if err := h.db.First(&targetUser, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	active, err := h.suspensions.Active(&targetUser)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check suspension"})
+		return
+	}
+
+	suspensions, err := h.suspensions.History(targetUser.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch suspensions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":           targetUser.UserID,
+		"active_suspension": active,
+		"suspensions":       suspensions,
+	})
+}
+
+// suspend suspends the user and logs the action.
+func (h *UserHandler) suspend(targetUser, moderator *models.User, reason string, duration time.Duration) (*models.Suspension, error) {
+	wasBanned := targetUser.IsBanned
+
+	var suspension *models.Suspension
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		suspension, err = services.NewSuspensionManager(tx).Suspend(targetUser, moderator, reason, duration)
+		if err != nil {
+			return err
+		}
+
+		return services.RecordModeration(tx, moderator, services.ModerationEntry{
+			Action:       services.ModerationSuspendUser,
+			TargetType:   services.ModerationTargetUser,
+			TargetID:     targetUser.UserID,
+			TargetUserID: targetUser.UserID,
+			Reason:       reason,
+			Before:       gin.H{"is_banned": wasBanned},
+			After:        gin.H{"is_banned": true, "suspension": suspension},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return suspension, nil
+}
+
+// lift lifts the suspensions of the user, restores the content they removed
+// and logs the action.
+func (h *UserHandler) lift(targetUser, moderator *models.User, reason string) (int64, error) {
+	active, err := h.suspensions.Active(targetUser)
+	if err != nil {
+		return 0, err
+	}
+
+	var restored int64
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		restored, err = services.NewSuspensionManager(tx).Lift(targetUser, moderator)
+		if err != nil {
+			return err
+		}
+
+		return services.RecordModeration(tx, moderator, services.ModerationEntry{
+			Action:       services.ModerationLiftSuspension,
+			TargetType:   services.ModerationTargetUser,
+			TargetID:     targetUser.UserID,
+			TargetUserID: targetUser.UserID,
+			Reason:       reason,
+			Before:       gin.H{"is_banned": true, "suspension": active},
+			After:        gin.H{"is_banned": false, "restored": restored},
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return restored, nil
+}
@@ -1,11 +1,28 @@
 package user
 
-import "gorm.io/gorm"
+import (
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
 
 type UserHandler struct {
-	db *gorm.DB
+	db          *gorm.DB
+	sessions    *services.SessionManager
+	emailTokens *services.EmailTokenManager
+	mailer      services.Mailer
+	hasher      services.PasswordHasher
+	rbac        *services.RBACManager
+	suspensions *services.SuspensionManager
 }
 
 func NewUserHandler(db *gorm.DB) *UserHandler {
-	return &UserHandler{db: db}
+	return &UserHandler{
+		db:          db,
+		sessions:    services.NewSessionManager(db),
+		emailTokens: services.NewEmailTokenManager(db),
+		mailer:      services.NewMailerFromEnv(),
+		hasher:      services.NewPasswordHasherFromEnv(),
+		rbac:        services.NewRBACManager(db),
+		suspensions: services.NewSuspensionManager(db),
+	}
 }
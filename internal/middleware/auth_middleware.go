@@ -1,53 +1,74 @@
 package middleware
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/oadultradeepfield/olympliance-server/internal/models"
 	"github.com/oadultradeepfield/olympliance-server/internal/services"
 	"gorm.io/gorm"
 )
 
-type Claims struct {
-	UserID uint
-	jwt.RegisteredClaims
-}
+var (
+	errAPITokenInvalid      = errors.New("invalid API token")
+	errAPITokenExpired      = errors.New("API token has expired or been revoked")
+	errAPITokenUserNotFound = errors.New("API token user not found")
+)
 
 func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
+	sessions := services.NewSessionManager(db)
+	impersonations := services.NewImpersonationManager(db)
+
 	return func(c *gin.Context) {
+		if authorization := c.GetHeader("Authorization"); authorization != "" {
+			token, found := strings.CutPrefix(authorization, "Bearer ")
+			switch {
+			case !found:
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header"})
+				c.Abort()
+			case services.IsAPIToken(token):
+				authenticateAPIToken(c, db, token)
+			default:
+				authenticateImpersonation(c, impersonations, token)
+			}
+			return
+		}
+
 		accessToken, err := c.Cookie("access_token")
 		if err != nil {
-			if !handleRefreshFlow(c, db) {
+			if !handleRefreshFlow(c, sessions) {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "No access token and refresh token failed"})
 				c.Abort()
 			}
 			return
 		}
 
-		claims := &Claims{}
-		secretKey := os.Getenv("JWT_SECRET")
-		if secretKey == "" {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "JWT_SECRET not set"})
+		if _, err := services.DefaultKeyRing(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "JWT signing keys not configured"})
 			c.Abort()
 			return
 		}
 
-		token, err := jwt.ParseWithClaims(accessToken, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secretKey), nil
-		})
-
-		if err != nil || !token.Valid {
-			if !handleRefreshFlow(c, db) {
+		claims, err := services.ParseToken(accessToken, services.AccessTokenType)
+		if err != nil {
+			if !handleRefreshFlow(c, sessions) {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid access token and refresh token failed"})
 				c.Abort()
 			}
 			return
 		}
 
+		if err := sessions.ValidateSession(claims.SessionID, claims.UserID); err != nil {
+			services.ClearAuthCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		}
+
 		var user models.User
 		if err := db.First(&user, claims.UserID).Error; err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
@@ -56,46 +77,207 @@ func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		c.Set("user", &user)
+		c.Set("session_id", claims.SessionID)
+	}
+}
+
+// OptionalAuthMiddleware sets the user on public routes the same ways
+// AuthMiddleware does, from the access token cookie, a refresh of the session,
+// an API token with the read scope, or an impersonation token. Everyone else
+// gets through as a guest, so it never rejects requests for their credentials.
+func OptionalAuthMiddleware(db *gorm.DB) gin.HandlerFunc {
+	sessions := services.NewSessionManager(db)
+	impersonations := services.NewImpersonationManager(db)
+
+	return func(c *gin.Context) {
+		if authorization := c.GetHeader("Authorization"); authorization != "" {
+			token, found := strings.CutPrefix(authorization, "Bearer ")
+			switch {
+			case !found:
+			case services.IsAPIToken(token):
+				apiToken, user, err := findAPIToken(db, token)
+				if err != nil || !services.Contains(apiToken.Scopes, services.ScopeRead) {
+					return
+				}
+				if err := useAPIToken(c, db, apiToken, user); err != nil {
+					log.Printf("Error recording use of API token %d: %v", apiToken.TokenID, err)
+				}
+			default:
+				impersonation, user, err := impersonations.Authenticate(token)
+				if err != nil {
+					return
+				}
+				actAsImpersonatedUser(c, impersonations, impersonation, user)
+			}
+			return
+		}
+
+		accessToken, err := c.Cookie("access_token")
+		if err != nil {
+			refreshOptionalSession(c, sessions)
+			return
+		}
+
+		claims, err := services.ParseToken(accessToken, services.AccessTokenType)
+		if err != nil {
+			refreshOptionalSession(c, sessions)
+			return
+		}
+
+		if err := sessions.ValidateSession(claims.SessionID, claims.UserID); err != nil {
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, claims.UserID).Error; err != nil {
+			return
+		}
+
+		c.Set("user", &user)
+		c.Set("session_id", claims.SessionID)
 	}
 }
 
-func handleRefreshFlow(c *gin.Context, db *gorm.DB) bool {
-	refreshToken, err := c.Cookie("refresh_token")
+// refreshOptionalSession is handleRefreshFlow for public routes, which carry on
+// as a guest when the session cannot be refreshed.
+func refreshOptionalSession(c *gin.Context, sessions *services.SessionManager) {
+	user, session, err := sessions.RefreshSession(c)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "No refresh token found"})
-		return false
+		if errors.Is(err, services.ErrRefreshTokenReused) || errors.Is(err, services.ErrSessionRevoked) {
+			services.ClearAuthCookies(c)
+		}
+		return
 	}
 
-	secretKey := os.Getenv("JWT_SECRET")
-	refreshClaims := &Claims{}
-	refreshTokenParsed, err := jwt.ParseWithClaims(refreshToken, refreshClaims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secretKey), nil
-	})
+	c.Set("user", user)
+	c.Set("session_id", session.SessionID)
+}
 
-	if err != nil || !refreshTokenParsed.Valid || refreshClaims.ExpiresAt.Before(time.Now()) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+func handleRefreshFlow(c *gin.Context, sessions *services.SessionManager) bool {
+	user, session, err := sessions.RefreshSession(c)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRefreshTokenReused):
+			services.ClearAuthCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected, session revoked"})
+		case errors.Is(err, services.ErrSessionRevoked):
+			services.ClearAuthCookies(c)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+		case errors.Is(err, services.ErrInvalidRefreshToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		}
 		return false
 	}
 
-	var user models.User
-	if err := db.First(&user, refreshClaims.UserID).Error; err != nil {
+	c.Set("user", user)
+	c.Set("session_id", session.SessionID)
+	return true
+}
+
+// authenticateAPIToken authenticates scripts and bots that send a personal
+// access token as an Authorization: Bearer header instead of cookies.
+func authenticateAPIToken(c *gin.Context, db *gorm.DB, token string) {
+	apiToken, user, err := findAPIToken(db, token)
+	switch {
+	case errors.Is(err, errAPITokenExpired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "API token has expired or been revoked"})
+		c.Abort()
+		return
+	case errors.Is(err, errAPITokenUserNotFound):
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-		return false
+		c.Abort()
+		return
+	case err != nil:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API token"})
+		c.Abort()
+		return
+	}
+
+	// Every request needs read or write depending on its method, and routes
+	// that moderate add RequireScope on top. Tokens cannot hold moderate
+	// without both, so the method check never blocks a moderation route.
+	requiredScope := services.ScopeWrite
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		requiredScope = services.ScopeRead
+	}
+	if !services.Contains(apiToken.Scopes, requiredScope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API token is missing the " + requiredScope + " scope"})
+		c.Abort()
+		return
+	}
+
+	if err := useAPIToken(c, db, apiToken, user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record API token use"})
+		c.Abort()
+		return
+	}
+}
+
+// findAPIToken looks up an active personal access token and the user it
+// belongs to.
+func findAPIToken(db *gorm.DB, token string) (*models.APIToken, *models.User, error) {
+	var apiToken models.APIToken
+	if err := db.Where("token_hash = ?", services.HashToken(token)).First(&apiToken).Error; err != nil {
+		return nil, nil, errAPITokenInvalid
+	}
+
+	if apiToken.RevokedAt != nil || (apiToken.ExpiresAt != nil && apiToken.ExpiresAt.Before(time.Now())) {
+		return nil, nil, errAPITokenExpired
+	}
+
+	var user models.User
+	if err := db.First(&user, apiToken.UserID).Error; err != nil || user.IsDeleted {
+		return nil, nil, errAPITokenUserNotFound
+	}
+
+	return &apiToken, &user, nil
+}
+
+// useAPIToken records that the token was used, at most once a minute, and
+// sets its user and scopes on the request.
+func useAPIToken(c *gin.Context, db *gorm.DB, apiToken *models.APIToken, user *models.User) error {
+	if apiToken.LastUsedAt == nil || time.Since(*apiToken.LastUsedAt) > time.Minute {
+		if err := db.Model(apiToken).Update("last_used_at", time.Now()).Error; err != nil {
+			return err
+		}
 	}
 
-	accessTokenClaims := Claims{
-		UserID: user.UserID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
-		},
+	c.Set("user", user)
+	c.Set("api_token_scopes", []string(apiToken.Scopes))
+	return nil
+}
+
+// authenticateImpersonation lets an admin act as another user with a token
+// from the impersonation endpoint. Every request is written to the audit log
+// before it is handled and refused if that fails, and the response status is
+// added once it has been handled.
+func authenticateImpersonation(c *gin.Context, impersonations *services.ImpersonationManager, token string) {
+	impersonation, user, err := impersonations.Authenticate(token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired impersonation token"})
+		c.Abort()
+		return
 	}
-	newAccessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims).SignedString([]byte(secretKey))
+
+	actAsImpersonatedUser(c, impersonations, impersonation, user)
+}
+
+func actAsImpersonatedUser(c *gin.Context, impersonations *services.ImpersonationManager, impersonation *models.Impersonation, user *models.User) {
+	entry, err := impersonations.RecordRequest(c, impersonation)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate new access token"})
-		return false
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record impersonated request"})
+		c.Abort()
+		return
 	}
 
-	services.SetCookie(c, "access_token", newAccessToken, 15*60)
-	c.Set("user", &user)
-	return true
+	c.Set("user", user)
+	c.Set("impersonation", impersonation)
+
+	c.Next()
+
+	if err := impersonations.RecordStatus(entry, c.Writer.Status()); err != nil {
+		log.Printf("Error recording status of impersonated request %d: %v", entry.ImpersonationLogID, err)
+	}
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
 	"gorm.io/gorm"
 )
 
@@ -24,8 +25,19 @@ func BanCheckMiddleware(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		if user.IsBanned {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Your account has been banned"})
+		suspension, err := services.NewSuspensionManager(db).Active(&user)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account suspension"})
+			c.Abort()
+			return
+		}
+
+		if suspension != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Your account has been suspended",
+				"reason":  suspension.Reason,
+				"ends_at": suspension.EndsAt,
+			})
 			c.Abort()
 			return
 		}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+// HasPermission reports whether the current user holds the permission through
+// one of their roles. Permissions are staff powers, so API tokens without the
+// moderate scope and impersonating admins never have them. The permissions
+// are loaded once per request.
+func HasPermission(c *gin.Context, db *gorm.DB, permission string) bool {
+	if !HasScope(c, services.ScopeModerate) {
+		return false
+	}
+
+	if cached, exists := c.Get("permissions"); exists {
+		permissions, _ := cached.([]string)
+		return services.Contains(permissions, permission)
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		return false
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		return false
+	}
+
+	permissions, err := services.NewRBACManager(db).Permissions(currentUser.UserID)
+	if err != nil {
+		log.Printf("Error loading permissions of user %d: %v", currentUser.UserID, err)
+		return false
+	}
+
+	c.Set("permissions", permissions)
+	return services.Contains(permissions, permission)
+}
+
+func RequirePermission(db *gorm.DB, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, db, permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have the " + permission + " permission"})
+			c.Abort()
+			return
+		}
+	}
+}
+
+// HasCategoryPermission is HasPermission for actions inside one category, which
+// moderators of that category may also take.
+func HasCategoryPermission(c *gin.Context, db *gorm.DB, permission string, categoryID uint) bool {
+	if !HasScope(c, services.ScopeModerate) {
+		return false
+	}
+
+	if HasPermission(c, db, permission) {
+		return true
+	}
+
+	user, exists := c.Get("user")
+	if !exists {
+		return false
+	}
+
+	currentUser, ok := user.(*models.User)
+	if !ok {
+		return false
+	}
+
+	allowed, err := services.NewRBACManager(db).HasCategoryPermission(currentUser.UserID, permission, categoryID)
+	if err != nil {
+		log.Printf("Error loading category permissions of user %d: %v", currentUser.UserID, err)
+		return false
+	}
+	return allowed
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+)
+
+// RateLimitMiddleware allows at most limit requests per client IP within a
+// sliding window. Requests over the limit get 429 with a Retry-After header.
+func RateLimitMiddleware(store services.AttemptStore, name string, limit int, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		hits, allowed, err := store.AddHit("ip:"+name+":"+c.ClientIP(), now, window, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check rate limit"})
+			c.Abort()
+			return
+		}
+
+		if !allowed {
+			// The window frees up once the oldest hit that counts against the
+			// limit falls out of it.
+			retryAfter := hits[0].Add(window).Sub(now)
+			c.Header("Retry-After", strconv.Itoa(services.RetryAfterSeconds(retryAfter)))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please try again later"})
+			c.Abort()
+			return
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+)
+
+// HasScope reports whether the request may act with the given scope. Cookie
+// sessions carry every scope, API tokens only the ones they were created with,
+// and impersonating admins everything except moderation.
+func HasScope(c *gin.Context, scope string) bool {
+	if IsImpersonating(c) {
+		return scope != services.ScopeModerate
+	}
+
+	scopes, exists := c.Get("api_token_scopes")
+	if !exists {
+		return true
+	}
+
+	tokenScopes, ok := scopes.([]string)
+	return ok && services.Contains(tokenScopes, scope)
+}
+
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsImpersonating(c) && !HasScope(c, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This action is not allowed while impersonating"})
+			c.Abort()
+			return
+		}
+
+		if !HasScope(c, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API token is missing the " + scope + " scope"})
+			c.Abort()
+			return
+		}
+	}
+}
+
+func IsImpersonating(c *gin.Context) bool {
+	_, exists := c.Get("impersonation")
+	return exists
+}
+
+// RequireSession keeps account management such as password changes and token
+// creation out of reach of API tokens and impersonating admins.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if IsImpersonating(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This action is not allowed while impersonating"})
+			c.Abort()
+			return
+		}
+
+		if _, exists := c.Get("api_token_scopes"); exists {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This action requires signing in"})
+			c.Abort()
+			return
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
+	"gorm.io/gorm"
+)
+
+// TwoFactorRequirementMiddleware blocks moderators and admins who have not
+// enrolled in two-factor authentication once an admin has made it mandatory.
+func TwoFactorRequirementMiddleware(db *gorm.DB) gin.HandlerFunc {
+	twoFactor := services.NewTwoFactorManager(db)
+
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			c.Abort()
+			return
+		}
+
+		currentUser, ok := user.(*models.User)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user data"})
+			c.Abort()
+			return
+		}
+
+		required, err := twoFactor.IsRequired(currentUser)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor requirement"})
+			c.Abort()
+			return
+		}
+
+		if !required {
+			return
+		}
+
+		enabled, err := twoFactor.IsEnabled(currentUser.UserID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor status"})
+			c.Abort()
+			return
+		}
+
+		if !enabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication is required for your role", "two_factor_required": true})
+			c.Abort()
+			return
+		}
+	}
+}
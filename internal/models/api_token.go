@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type APIToken struct {
+	TokenID     uint           `gorm:"primaryKey;autoIncrement" json:"token_id"`
+	UserID      uint           `gorm:"not null;index" json:"user_id"`
+	Name        string         `gorm:"not null" json:"name"`
+	TokenPrefix string         `gorm:"not null" json:"token_prefix"`
+	TokenHash   string         `gorm:"not null;uniqueIndex" json:"-"`
+	Scopes      pq.StringArray `gorm:"type:text[]" json:"scopes"`
+	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt   *time.Time     `gorm:"default:null" json:"expires_at"`
+	LastUsedAt  *time.Time     `gorm:"default:null" json:"last_used_at"`
+	RevokedAt   *time.Time     `gorm:"default:null" json:"revoked_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+type AutomodMatch struct {
+	AutomodMatchID uint      `gorm:"primaryKey;autoIncrement" json:"match_id"`
+	RuleID         uint      `gorm:"not null;index" json:"rule_id"`
+	UserID         uint      `gorm:"not null;index" json:"user_id"`
+	CategoryID     uint      `gorm:"not null" json:"category_id"`
+	TargetType     string    `gorm:"not null" json:"target_type"`
+	TargetID       *uint     `gorm:"default:null" json:"target_id"`
+	Action         string    `gorm:"not null" json:"action"`
+	Match          string    `gorm:"" json:"match"`
+	CreatedAt      time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+}
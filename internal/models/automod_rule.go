@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type AutomodRule struct {
+	AutomodRuleID uint           `gorm:"primaryKey;autoIncrement" json:"rule_id"`
+	Name          string         `gorm:"not null" json:"name"`
+	Type          string         `gorm:"not null" json:"type"`
+	Keywords      pq.StringArray `gorm:"type:text[]" json:"keywords"`
+	Pattern       string         `gorm:"" json:"pattern"`
+	Threshold     float64        `gorm:"not null;default:0" json:"threshold"`
+	CategoryID    *uint          `gorm:"default:null;index" json:"category_id"`
+	Action        string         `gorm:"not null" json:"action"`
+	Message       string         `gorm:"" json:"message"`
+	IsEnabled     bool           `gorm:"not null" json:"is_enabled"`
+	CreatedBy     uint           `gorm:"not null" json:"created_by"`
+	CreatedAt     time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+type CategoryModerator struct {
+	UserID     uint      `gorm:"primaryKey" json:"user_id"`
+	CategoryID uint      `gorm:"primaryKey;index" json:"category_id"`
+	AssignedBy uint      `gorm:"not null" json:"assigned_by"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
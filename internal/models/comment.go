@@ -15,4 +15,9 @@ type Comment struct {
 	CreatedAt       time.Time       `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt       time.Time       `gorm:"autoUpdateTime" json:"updated_at"`
 	IsDeleted       bool            `gorm:"default:false" json:"is_deleted"`
+	RemovalReason   string          `gorm:"" json:"removal_reason"`
+	RemovedBy       *uint           `gorm:"default:null" json:"removed_by"`
+	RemovedAt       *time.Time      `gorm:"default:null" json:"removed_at"`
+	SuspensionID    *uint           `gorm:"default:null;index" json:"suspension_id"`
+	Visibility      string          `gorm:"default:'visible';not null;index" json:"visibility"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+type EmailToken struct {
+	EmailTokenID uint       `gorm:"primaryKey;autoIncrement" json:"email_token_id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	Purpose      string     `gorm:"not null" json:"purpose"`
+	Email        string     `gorm:"not null" json:"email"`
+	TokenHash    string     `gorm:"not null;uniqueIndex" json:"-"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt       *time.Time `gorm:"default:null" json:"used_at"`
+}
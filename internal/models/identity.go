@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+type Identity struct {
+	IdentityID uint      `gorm:"primaryKey;autoIncrement" json:"identity_id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_identities_user_provider" json:"user_id"`
+	Provider   string    `gorm:"not null;uniqueIndex:idx_identities_provider_subject;uniqueIndex:idx_identities_user_provider" json:"provider"`
+	Subject    string    `gorm:"not null;uniqueIndex:idx_identities_provider_subject" json:"-"`
+	Email      string    `gorm:"" json:"email"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+type Impersonation struct {
+	ImpersonationID uint       `gorm:"primaryKey;autoIncrement" json:"impersonation_id"`
+	AdminID         uint       `gorm:"not null;index" json:"admin_id"`
+	TargetUserID    uint       `gorm:"not null;index" json:"target_user_id"`
+	Reason          string     `gorm:"not null" json:"reason"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt       time.Time  `gorm:"not null" json:"expires_at"`
+	EndedAt         *time.Time `gorm:"default:null" json:"ended_at"`
+}
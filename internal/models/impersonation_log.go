@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+type ImpersonationLog struct {
+	ImpersonationLogID uint      `gorm:"primaryKey;autoIncrement" json:"log_id"`
+	ImpersonationID    uint      `gorm:"not null;index" json:"impersonation_id"`
+	AdminID            uint      `gorm:"not null;index" json:"admin_id"`
+	TargetUserID       uint      `gorm:"not null;index" json:"target_user_id"`
+	Method             string    `gorm:"not null" json:"method"`
+	Path               string    `gorm:"not null" json:"path"`
+	StatusCode         int       `gorm:"not null" json:"status_code"`
+	IPAddress          string    `gorm:"" json:"ip_address"`
+	UserAgent          string    `gorm:"" json:"user_agent"`
+	CreatedAt          time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
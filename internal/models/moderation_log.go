@@ -0,0 +1,19 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ModerationLog struct {
+	ModerationLogID uint            `gorm:"primaryKey;autoIncrement" json:"log_id"`
+	ActorID         uint            `gorm:"not null;index" json:"actor_id"`
+	Action          string          `gorm:"not null;index" json:"action"`
+	TargetType      string          `gorm:"not null" json:"target_type"`
+	TargetID        uint            `gorm:"not null" json:"target_id"`
+	TargetUserID    uint            `gorm:"not null;index" json:"target_user_id"`
+	Reason          string          `gorm:"" json:"reason"`
+	Before          json.RawMessage `gorm:"type:jsonb" json:"before"`
+	After           json.RawMessage `gorm:"type:jsonb" json:"after"`
+	CreatedAt       time.Time       `gorm:"autoCreateTime;index" json:"created_at"`
+}
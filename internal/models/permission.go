@@ -0,0 +1,7 @@
+package models
+
+type Permission struct {
+	PermissionID uint   `gorm:"primaryKey;autoIncrement" json:"permission_id"`
+	Name         string `gorm:"not null;uniqueIndex" json:"name"`
+	Description  string `gorm:"" json:"description"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+type Report struct {
+	ReportID       uint       `gorm:"primaryKey;autoIncrement" json:"report_id"`
+	ReporterID     uint       `gorm:"not null;index" json:"reporter_id"`
+	TargetType     string     `gorm:"not null;index:idx_reports_target" json:"target_type"`
+	TargetID       uint       `gorm:"not null;index:idx_reports_target" json:"target_id"`
+	TargetUserID   uint       `gorm:"not null;index" json:"target_user_id"`
+	CategoryID     uint       `gorm:"not null" json:"category_id"`
+	Reason         string     `gorm:"not null" json:"reason"`
+	Note           string     `gorm:"" json:"note"`
+	Status         string     `gorm:"default:'open';not null;index" json:"status"`
+	Resolution     string     `gorm:"" json:"resolution"`
+	ResolutionNote string     `gorm:"" json:"resolution_note"`
+	ResolvedBy     *uint      `gorm:"default:null" json:"resolved_by"`
+	ResolvedAt     *time.Time `gorm:"default:null" json:"resolved_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+type Role struct {
+	RoleID      uint      `gorm:"primaryKey;autoIncrement" json:"role_id"`
+	Name        string    `gorm:"not null;uniqueIndex" json:"name"`
+	Description string    `gorm:"" json:"description"`
+	Level       int       `gorm:"default:0;not null" json:"level"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
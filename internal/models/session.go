@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+type Session struct {
+	SessionID        uint       `gorm:"primaryKey;autoIncrement" json:"session_id"`
+	UserID           uint       `gorm:"not null;index" json:"user_id"`
+	RefreshTokenHash string     `gorm:"not null" json:"-"`
+	UserAgent        string     `gorm:"" json:"user_agent"`
+	IPAddress        string     `gorm:"" json:"ip_address"`
+	CreatedAt        time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	LastUsedAt       time.Time  `gorm:"not null" json:"last_used_at"`
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt        *time.Time `gorm:"default:null" json:"revoked_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+type Suspension struct {
+	SuspensionID uint       `gorm:"primaryKey;autoIncrement" json:"suspension_id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	ModeratorID  *uint      `gorm:"default:null" json:"moderator_id"`
+	Reason       string     `gorm:"not null" json:"reason"`
+	StartsAt     time.Time  `gorm:"not null" json:"starts_at"`
+	EndsAt       *time.Time `gorm:"default:null" json:"ends_at"`
+	LiftedAt     *time.Time `gorm:"default:null" json:"lifted_at"`
+	LiftedBy     *uint      `gorm:"default:null" json:"lifted_by"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+type TwoFactor struct {
+	UserID       uint       `gorm:"primaryKey" json:"user_id"`
+	Secret       string     `gorm:"not null" json:"-"`
+	IsEnabled    bool       `gorm:"default:false" json:"is_enabled"`
+	LastUsedStep int64      `gorm:"default:0" json:"-"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	EnabledAt    *time.Time `gorm:"default:null" json:"enabled_at"`
+}
+
+type RecoveryCode struct {
+	RecoveryCodeID uint       `gorm:"primaryKey;autoIncrement" json:"recovery_code_id"`
+	UserID         uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash       string     `gorm:"not null" json:"-"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UsedAt         *time.Time `gorm:"default:null" json:"used_at"`
+}
@@ -5,13 +5,15 @@ import (
 )
 
 type User struct {
-	UserID       uint      `gorm:"primaryKey;autoIncrement" json:"user_id"`
-	Username     string    `gorm:"unique;not null" json:"username"`
-	PasswordHash string    `gorm:"not null" json:"password_hash"`
-	RoleID       int       `gorm:"default:0;not null" json:"role_id"`
-	Reputation   int       `gorm:"default:0" json:"reputation"`
-	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
-	GoogleID     string    `gorm:"default:null" json:"google_id"`
-	IsBanned     bool      `gorm:"default:false" json:"is_banned"`
-	IsDeleted    bool      `gorm:"default:false" json:"is_deleted"`
+	UserID          uint       `gorm:"primaryKey;autoIncrement" json:"user_id"`
+	Username        string     `gorm:"unique;not null" json:"username"`
+	PasswordHash    string     `gorm:"not null" json:"password_hash"`
+	Email           *string    `gorm:"uniqueIndex;default:null" json:"email"`
+	EmailVerifiedAt *time.Time `gorm:"default:null" json:"email_verified_at"`
+	RoleID          int        `gorm:"default:0;not null" json:"role_id"`
+	Reputation      int        `gorm:"default:0" json:"reputation"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	IsBanned        bool       `gorm:"default:false" json:"is_banned"`
+	IsDeleted       bool       `gorm:"default:false" json:"is_deleted"`
+	NeedsOnboarding bool       `gorm:"default:false" json:"needs_onboarding"`
 }
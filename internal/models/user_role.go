@@ -0,0 +1,11 @@
+package models
+
+import (
+	"time"
+)
+
+type UserRole struct {
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	RoleID    uint      `gorm:"primaryKey;index" json:"role_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebAuthnCredential struct {
+	WebAuthnCredentialID uint           `gorm:"primaryKey;autoIncrement" json:"passkey_id"`
+	UserID               uint           `gorm:"not null;index" json:"user_id"`
+	Name                 string         `gorm:"not null" json:"name"`
+	CredentialID         []byte         `gorm:"not null;uniqueIndex" json:"-"`
+	PublicKey            []byte         `gorm:"not null" json:"-"`
+	AttestationType      string         `gorm:"" json:"-"`
+	Transports           pq.StringArray `gorm:"type:text[]" json:"-"`
+	AAGUID               []byte         `gorm:"" json:"-"`
+	SignCount            uint32         `gorm:"default:0" json:"-"`
+	UserVerified         bool           `gorm:"default:false" json:"-"`
+	BackupEligible       bool           `gorm:"default:false" json:"-"`
+	BackupState          bool           `gorm:"default:false" json:"-"`
+	CreatedAt            time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	LastUsedAt           *time.Time     `gorm:"default:null" json:"last_used_at"`
+}
@@ -1,22 +1,50 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/oadultradeepfield/olympliance-server/internal/handlers/auth"
+	"github.com/oadultradeepfield/olympliance-server/internal/handlers/automod"
+	"github.com/oadultradeepfield/olympliance-server/internal/handlers/category"
 	"github.com/oadultradeepfield/olympliance-server/internal/handlers/comment"
+	"github.com/oadultradeepfield/olympliance-server/internal/handlers/impersonation"
 	"github.com/oadultradeepfield/olympliance-server/internal/handlers/interaction"
+	"github.com/oadultradeepfield/olympliance-server/internal/handlers/moderation"
+	"github.com/oadultradeepfield/olympliance-server/internal/handlers/passkey"
+	"github.com/oadultradeepfield/olympliance-server/internal/handlers/premoderation"
+	"github.com/oadultradeepfield/olympliance-server/internal/handlers/report"
+	"github.com/oadultradeepfield/olympliance-server/internal/handlers/role"
+	"github.com/oadultradeepfield/olympliance-server/internal/handlers/session"
 	"github.com/oadultradeepfield/olympliance-server/internal/handlers/thread"
+	"github.com/oadultradeepfield/olympliance-server/internal/handlers/token"
+	"github.com/oadultradeepfield/olympliance-server/internal/handlers/twofactor"
 	"github.com/oadultradeepfield/olympliance-server/internal/handlers/user"
 	"github.com/oadultradeepfield/olympliance-server/internal/middleware"
+	"github.com/oadultradeepfield/olympliance-server/internal/services"
 	"gorm.io/gorm"
 )
 
 func InitRoutes(r *gin.Engine, db *gorm.DB) {
-	authHandler := auth.NewAuthHandler(db)
+	attemptStore := services.NewInMemoryAttemptStore()
+	passkeys := services.NewPasskeyManager(db, attemptStore)
+
+	authHandler := auth.NewAuthHandler(db, attemptStore, passkeys)
 	userHandler := user.NewUserHandler(db)
 	threadHandler := thread.NewThreadHandler(db)
 	commentHandler := comment.NewCommentHandler(db)
 	interactionHandler := interaction.NewInteractionHandler(db)
+	sessionHandler := session.NewSessionHandler(db)
+	tokenHandler := token.NewTokenHandler(db)
+	twoFactorHandler := twofactor.NewTwoFactorHandler(db)
+	passkeyHandler := passkey.NewPasskeyHandler(db, passkeys)
+	impersonationHandler := impersonation.NewImpersonationHandler(db)
+	roleHandler := role.NewRoleHandler(db)
+	categoryHandler := category.NewCategoryHandler(db)
+	moderationHandler := moderation.NewModerationHandler(db)
+	reportHandler := report.NewReportHandler(db)
+	automodHandler := automod.NewAutomodHandler(db)
+	premoderationHandler := premoderation.NewPremoderationHandler(db)
 
 	r.Use(middleware.CorsMiddleware())
 
@@ -24,38 +52,132 @@ func InitRoutes(r *gin.Engine, db *gorm.DB) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	r.GET("/.well-known/jwks.json", authHandler.GetJWKS)
+
 	// Unprotected Routes
+	optionalAuth := middleware.OptionalAuthMiddleware(db)
 	r.GET("/api/userinfo", userHandler.GetUserInformation)
 	r.GET("/api/leaderboard", userHandler.GetLeaderboard)
-	r.GET("/api/threads/:id", threadHandler.GetThread)
-	r.GET("/api/threads/category/:category_id", threadHandler.GetAllThreadsByCategory)
-	r.GET("/api/comments", commentHandler.GetAllComments)
+	r.GET("/api/threads/:id", optionalAuth, threadHandler.GetThread)
+	r.GET("/api/threads/category/:category_id", optionalAuth, threadHandler.GetAllThreadsByCategory)
+	r.GET("/api/categories/moderators", categoryHandler.GetCategoryModerators)
+	r.GET("/api/comments", optionalAuth, commentHandler.GetAllComments)
 	r.GET("/api/interactions", interactionHandler.GetInteraction)
 
 	// Authentication Routes
-	r.POST("/api/register", authHandler.Register)
-	r.POST("/api/login", authHandler.Login)
-	r.GET("/api/auth/google/", authHandler.GoogleLogin)
-	r.GET("/api/auth/google/callback", authHandler.GoogleCallback)
+	authRateLimit := middleware.RateLimitMiddleware(attemptStore, "auth", 20, time.Minute)
+	registerRateLimit := middleware.RateLimitMiddleware(attemptStore, "register", 5, time.Hour)
+	magicLinkRateLimit := middleware.RateLimitMiddleware(attemptStore, "magic-link", 5, time.Hour)
+	r.POST("/api/register", authRateLimit, registerRateLimit, authHandler.Register)
+	r.POST("/api/login", authRateLimit, authHandler.Login)
+	r.POST("/api/login/2fa", authRateLimit, authHandler.VerifyTwoFactorLogin)
+	r.POST("/api/login/magic-link", authRateLimit, magicLinkRateLimit, authHandler.RequestMagicLink)
+	r.POST("/api/login/magic-link/verify", authRateLimit, authHandler.VerifyMagicLink)
+	r.POST("/api/login/passkey/begin", authRateLimit, authHandler.BeginPasskeyLogin)
+	r.POST("/api/login/passkey/finish", authRateLimit, authHandler.FinishPasskeyLogin)
+	r.POST("/api/verify-email", authRateLimit, authHandler.VerifyEmail)
+	r.POST("/api/forgot-password", authRateLimit, authHandler.ForgotPassword)
+	r.POST("/api/reset-password", authRateLimit, authHandler.ResetPassword)
+	r.GET("/api/auth/:provider/", authRateLimit, authHandler.OAuthLogin)
+	r.GET("/api/auth/:provider/callback", authRateLimit, authHandler.OAuthCallback)
+	r.GET("/api/csrf-token", authHandler.GetCSRFToken)
 
 	// Protected Routes
 	api := r.Group("/api")
-	api.Use(middleware.AuthMiddleware(db))
+	api.Use(middleware.CSRFMiddleware(), middleware.AuthMiddleware(db))
 
 	// Users
 	api.GET("/users", userHandler.GetCurrentUserInformation)
-	api.DELETE("/users/delete", userHandler.DeleteUser)
+	api.DELETE("/users/delete", middleware.RequireSession(), userHandler.DeleteUser)
+	api.PUT("/users/email", middleware.RequireSession(), userHandler.ChangeEmail)
+	api.POST("/users/email/resend-verification", middleware.RequireSession(), userHandler.ResendVerificationEmail)
 	api.POST("/logout", authHandler.Logout)
 
+	// Sessions
+	api.GET("/sessions", middleware.RequireSession(), sessionHandler.GetActiveSessions)
+	api.DELETE("/sessions/others", middleware.RequireSession(), sessionHandler.RevokeOtherSessions)
+	api.DELETE("/sessions/:id", middleware.RequireSession(), sessionHandler.RevokeSession)
+
+	// API Tokens
+	api.GET("/tokens", middleware.RequireSession(), tokenHandler.GetTokens)
+	api.POST("/tokens", middleware.RequireSession(), tokenHandler.CreateToken)
+	api.DELETE("/tokens/:id", middleware.RequireSession(), tokenHandler.RevokeToken)
+
+	// Two-Factor Authentication
+	api.GET("/2fa", middleware.RequireSession(), twoFactorHandler.GetTwoFactorStatus)
+	api.POST("/2fa/enroll", middleware.RequireSession(), twoFactorHandler.EnrollTwoFactor)
+	api.POST("/2fa/verify", middleware.RequireSession(), twoFactorHandler.VerifyTwoFactor)
+	api.POST("/2fa/recovery-codes", middleware.RequireSession(), twoFactorHandler.RegenerateRecoveryCodes)
+	api.POST("/2fa/disable", middleware.RequireSession(), twoFactorHandler.DisableTwoFactor)
+
+	// Passkeys
+	api.GET("/passkeys", middleware.RequireSession(), passkeyHandler.GetPasskeys)
+	api.POST("/passkeys/register/begin", middleware.RequireSession(), passkeyHandler.BeginRegistration)
+	api.POST("/passkeys/register/finish", middleware.RequireSession(), passkeyHandler.FinishRegistration)
+	api.DELETE("/passkeys/:id", middleware.RequireSession(), passkeyHandler.DeletePasskey)
+
 	// Below are routes protected from banned users
 	api.Use(middleware.BanCheckMiddleware(db))
 
+	// Below are routes that require moderators to use two-factor authentication when enforced
+	api.Use(middleware.TwoFactorRequirementMiddleware(db))
+
+	// Admin
+	requireImpersonate := middleware.RequirePermission(db, services.PermissionUserImpersonate)
+	requireRoleAssign := middleware.RequirePermission(db, services.PermissionRoleAssign)
+	api.PUT("/admin/require-moderator-2fa", middleware.RequireSession(), middleware.RequirePermission(db, services.PermissionSettingsManage), twoFactorHandler.SetModeratorRequirement)
+	api.POST("/admin/impersonate/:id", middleware.RequireSession(), requireImpersonate, impersonationHandler.StartImpersonation)
+	api.GET("/admin/impersonations", middleware.RequireSession(), requireImpersonate, impersonationHandler.GetImpersonations)
+	api.GET("/admin/impersonations/:id/logs", middleware.RequireSession(), requireImpersonate, impersonationHandler.GetImpersonationLogs)
+	api.DELETE("/admin/impersonations/:id", middleware.RequireSession(), requireImpersonate, impersonationHandler.EndImpersonation)
+	api.GET("/admin/roles", requireRoleAssign, roleHandler.GetRoles)
+	api.GET("/admin/users/:id/roles", requireRoleAssign, roleHandler.GetUserRoles)
+	api.PUT("/admin/users/:id/roles", middleware.RequireSession(), requireRoleAssign, roleHandler.SetUserRoles)
+	api.GET("/admin/premoderation", middleware.RequirePermission(db, services.PermissionSettingsManage), premoderationHandler.GetThresholds)
+	api.PUT("/admin/premoderation", middleware.RequireSession(), middleware.RequirePermission(db, services.PermissionSettingsManage), premoderationHandler.SetThresholds)
+
+	// Moderation
+	api.GET("/moderation-logs", middleware.RequireScope(services.ScopeModerate), middleware.RequirePermission(db, services.PermissionModerationLogView), moderationHandler.GetModerationLogs)
+
+	// Reports
+	reportRateLimit := middleware.RateLimitMiddleware(attemptStore, "report", 20, time.Hour)
+	api.POST("/reports", reportRateLimit, reportHandler.CreateReport)
+	api.GET("/reports", reportHandler.GetMyReports)
+	api.GET("/reports/queue", middleware.RequireScope(services.ScopeModerate), reportHandler.GetReportQueue)
+	api.POST("/reports/resolve", middleware.RequireScope(services.ScopeModerate), reportHandler.ResolveReports)
+
+	// Pre-moderation
+	api.GET("/pending", middleware.RequireScope(services.ScopeModerate), premoderationHandler.GetPendingPosts)
+	api.POST("/pending/review", middleware.RequireScope(services.ScopeModerate), premoderationHandler.ReviewPost)
+
+	// Automod
+	requireAutomodManage := middleware.RequirePermission(db, services.PermissionAutomodManage)
+	api.GET("/automod/rules", middleware.RequireScope(services.ScopeModerate), requireAutomodManage, automodHandler.GetRules)
+	api.POST("/automod/rules", middleware.RequireScope(services.ScopeModerate), requireAutomodManage, automodHandler.CreateRule)
+	api.PUT("/automod/rules/:id", middleware.RequireScope(services.ScopeModerate), requireAutomodManage, automodHandler.UpdateRule)
+	api.DELETE("/automod/rules/:id", middleware.RequireScope(services.ScopeModerate), requireAutomodManage, automodHandler.DeleteRule)
+	api.POST("/automod/test", middleware.RequireScope(services.ScopeModerate), requireAutomodManage, automodHandler.TestRules)
+	api.GET("/automod/matches", middleware.RequireScope(services.ScopeModerate), requireAutomodManage, automodHandler.GetMatches)
+
 	// Users
-	api.GET("/users/get-id/:username", userHandler.GetUserIDbyUsername)
+	api.GET("/users/get-id/:username", middleware.RequireScope(services.ScopeModerate), middleware.RequirePermission(db, services.PermissionUserLookup), userHandler.GetUserIDbyUsername)
 	api.PUT("/users/change-username", userHandler.ChangeUsername)
-	api.PUT("/users/change-password", userHandler.ChangePassword)
-	api.PUT("/users/:id/toggle-ban", userHandler.ToggleBanUser)
-	api.PUT("/users/:id/toggle-moderator", userHandler.ToggleAssignModerator)
+	api.PUT("/users/change-password", middleware.RequireSession(), userHandler.ChangePassword)
+	api.PUT("/users/set-password", middleware.RequireSession(), userHandler.SetPassword)
+	api.GET("/users/identities", userHandler.GetLinkedIdentities)
+	api.GET("/auth/:provider/link", middleware.RequireSession(), authHandler.OAuthLink)
+	api.DELETE("/auth/:provider", middleware.RequireSession(), authHandler.UnlinkProvider)
+	api.PUT("/users/:id/toggle-ban", middleware.RequireScope(services.ScopeModerate), middleware.RequirePermission(db, services.PermissionUserBan), userHandler.ToggleBanUser)
+	api.POST("/users/:id/suspensions", middleware.RequireScope(services.ScopeModerate), middleware.RequirePermission(db, services.PermissionUserBan), userHandler.SuspendUser)
+	api.DELETE("/users/:id/suspensions", middleware.RequireScope(services.ScopeModerate), middleware.RequirePermission(db, services.PermissionUserBan), userHandler.LiftSuspension)
+	api.GET("/users/:id/suspensions", middleware.RequireScope(services.ScopeModerate), middleware.RequirePermission(db, services.PermissionUserBan), userHandler.GetSuspensions)
+	api.GET("/users/:id/suspensions/restorable", middleware.RequireScope(services.ScopeModerate), middleware.RequirePermission(db, services.PermissionUserBan), userHandler.GetRestorableContent)
+	api.PUT("/users/:id/toggle-moderator", middleware.RequireScope(services.ScopeModerate), middleware.RequirePermission(db, services.PermissionModeratorAssign), userHandler.ToggleAssignModerator)
+	api.PUT("/users/:id/moderated-categories", middleware.RequireScope(services.ScopeModerate), middleware.RequirePermission(db, services.PermissionCategoryManage), userHandler.SetModeratedCategories)
+	api.PUT("/users/complete-onboarding", middleware.RequireSession(), userHandler.CompleteOnboarding)
+
+	// Below are routes that require a confirmed username
+	api.Use(middleware.OnboardingCheckMiddleware())
 
 	// Threads
 	api.POST("/threads", threadHandler.CreateThread)
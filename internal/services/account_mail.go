@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+)
+
+func SendVerificationEmail(ctx context.Context, mailer Mailer, tokens *EmailTokenManager, user *models.User, email string) error {
+	token, err := tokens.Issue(user.UserID, EmailTokenVerifyEmail, email, VerifyEmailTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	link := FrontendLink("verify-email", url.Values{"token": {token}})
+	return mailer.Send(ctx, Mail{
+		To:      email,
+		Subject: "Verify your Olympliance email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm this email address for your Olympliance account by opening the link below:\n\n%s\n\nThe link expires in 24 hours. If you did not add this address, you can ignore this email.\n",
+			user.Username, link,
+		),
+	})
+}
+
+func SendPasswordResetEmail(ctx context.Context, mailer Mailer, tokens *EmailTokenManager, user *models.User, email string) error {
+	token, err := tokens.Issue(user.UserID, EmailTokenResetPassword, email, ResetPasswordTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	link := FrontendLink("reset-password", url.Values{"token": {token}})
+	return mailer.Send(ctx, Mail{
+		To:      email,
+		Subject: "Reset your Olympliance password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to reset the password of your Olympliance account. Open the link below to choose a new one:\n\n%s\n\nThe link expires in 30 minutes and can only be used once. If you did not ask for this, you can ignore this email.\n",
+			user.Username, link,
+		),
+	})
+}
+
+func SendMagicLinkEmail(ctx context.Context, mailer Mailer, tokens *EmailTokenManager, user *models.User, email string) error {
+	token, err := tokens.Issue(user.UserID, EmailTokenMagicLink, email, MagicLinkTokenDuration)
+	if err != nil {
+		return err
+	}
+
+	link := FrontendLink("magic-link", url.Values{"token": {token}})
+	return mailer.Send(ctx, Mail{
+		To:      email,
+		Subject: "Your Olympliance sign-in link",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nOpen the link below to sign in to your Olympliance account:\n\n%s\n\nThe link expires in 15 minutes and can only be used once. If you did not ask for this, you can ignore this email.\n",
+			user.Username, link,
+		),
+	})
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"strings"
+)
+
+const (
+	ScopeRead     = "read"
+	ScopeWrite    = "write"
+	ScopeModerate = "moderate"
+
+	// APITokenPrefix marks personal access tokens so they can be told apart
+	// from JWTs and spotted by secret scanners.
+	APITokenPrefix = "olym_"
+)
+
+var APITokenScopes = []string{ScopeRead, ScopeWrite, ScopeModerate}
+
+// GenerateAPIToken returns a new personal access token, the short prefix shown
+// in token listings, and the digest stored in the database.
+func GenerateAPIToken() (token, displayPrefix, hash string, err error) {
+	secret, err := RandomToken(32)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	token = APITokenPrefix + secret
+	return token, token[:len(APITokenPrefix)+6], HashToken(token), nil
+}
+
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
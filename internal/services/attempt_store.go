@@ -0,0 +1,123 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// AttemptStore keeps the counters used to throttle authentication. The
+// in-memory implementation is enough for a single instance; a shared store
+// such as Redis can implement the same interface when running several.
+type AttemptStore interface {
+	// AddHit records a hit for key unless limit hits already happened within
+	// window. It returns the hits within window, including this one when it
+	// was recorded, and whether it was. Rejected hits are not recorded, so a
+	// client hammering the limit cannot grow the store or push the window out.
+	AddHit(key string, at time.Time, window time.Duration, limit int) ([]time.Time, bool, error)
+	// AddFailure increments the consecutive failure count of key.
+	AddFailure(key string, at time.Time) (int, error)
+	// Failures returns the consecutive failure count of key and when the last
+	// failure happened.
+	Failures(key string) (int, time.Time, error)
+	ResetFailures(key string) error
+}
+
+type failureRecord struct {
+	count int
+	last  time.Time
+}
+
+type InMemoryAttemptStore struct {
+	mu       sync.Mutex
+	hits     map[string][]time.Time
+	failures map[string]failureRecord
+	// retention bounds how long idle keys are kept before being pruned.
+	retention time.Duration
+}
+
+func NewInMemoryAttemptStore() *InMemoryAttemptStore {
+	store := &InMemoryAttemptStore{
+		hits:      map[string][]time.Time{},
+		failures:  map[string]failureRecord{},
+		retention: 24 * time.Hour,
+	}
+	go store.pruneEvery(time.Minute)
+	return store
+}
+
+func (s *InMemoryAttemptStore) AddHit(key string, at time.Time, window time.Duration, limit int) ([]time.Time, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hits := withinWindow(s.hits[key], at, window)
+	allowed := len(hits) < limit
+	if allowed {
+		hits = append(hits, at)
+	}
+	s.hits[key] = hits
+
+	return append([]time.Time(nil), hits...), allowed, nil
+}
+
+func (s *InMemoryAttemptStore) AddFailure(key string, at time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.failures[key]
+	record.count++
+	record.last = at
+	s.failures[key] = record
+
+	return record.count, nil
+}
+
+func (s *InMemoryAttemptStore) Failures(key string) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.failures[key]
+	return record.count, record.last, nil
+}
+
+func (s *InMemoryAttemptStore) ResetFailures(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, key)
+	return nil
+}
+
+func (s *InMemoryAttemptStore) pruneEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.prune(now)
+	}
+}
+
+func (s *InMemoryAttemptStore) prune(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, hits := range s.hits {
+		if len(hits) == 0 || now.Sub(hits[len(hits)-1]) > s.retention {
+			delete(s.hits, key)
+		}
+	}
+
+	for key, record := range s.failures {
+		if now.Sub(record.last) > s.retention {
+			delete(s.failures, key)
+		}
+	}
+}
+
+func withinWindow(hits []time.Time, now time.Time, window time.Duration) []time.Time {
+	cutoff := now.Add(-window)
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	return hits[i:]
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	AutomodKeyword    = "keyword"
+	AutomodRegex      = "regex"
+	AutomodLinkCount  = "link_count"
+	AutomodCapsRatio  = "caps_ratio"
+	AutomodAccountAge = "account_age"
+)
+
+const (
+	AutomodFlag   = "flag"
+	AutomodHold   = "hold"
+	AutomodReject = "reject"
+)
+
+// Held threads and comments wait for their automod report to be reviewed, and
+// pending ones for a moderator to approve them. Only their authors see them.
+const (
+	VisibilityVisible = "visible"
+	VisibilityHeld    = "held"
+	VisibilityPending = "pending"
+)
+
+// automodMinCapsLetters keeps short posts such as "OK" from tripping all-caps
+// rules.
+const automodMinCapsLetters = 10
+
+const automodMatchLength = 200
+
+// automodPatternCacheSize bounds the compiled patterns kept in memory, since
+// every edit of a rule adds one.
+const automodPatternCacheSize = 1000
+
+var AutomodRuleTypes = []string{AutomodKeyword, AutomodRegex, AutomodLinkCount, AutomodCapsRatio, AutomodAccountAge}
+
+// AutomodActions are ordered from the mildest to the strictest.
+var AutomodActions = []string{AutomodFlag, AutomodHold, AutomodReject}
+
+var ErrInvalidAutomodRule = errors.New("invalid automod rule")
+
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://\S+|\bwww\.\S+`)
+
+// automodPatterns caches the compiled patterns of rules by their source, so
+// posts are not checked against freshly compiled regexps every time. Keying on
+// the source also covers draft rules, which have no ID yet.
+var automodPatterns = struct {
+	sync.Mutex
+	compiled map[string]*regexp.Regexp
+}{compiled: map[string]*regexp.Regexp{}}
+
+// AutomodPost is a thread or comment about to be saved. Account age rules are
+// skipped when AccountCreatedAt is nil.
+type AutomodPost struct {
+	TargetType       string
+	UserID           uint
+	CategoryID       uint
+	Text             string
+	AccountCreatedAt *time.Time
+}
+
+type AutomodHit struct {
+	RuleID  uint   `json:"rule_id"`
+	Name    string `json:"name"`
+	Action  string `json:"action"`
+	Match   string `json:"match"`
+	Message string `json:"message"`
+}
+
+// AutomodVerdict holds every rule a post matched and the strictest of their
+// actions, which is empty when nothing matched.
+type AutomodVerdict struct {
+	Action string       `json:"action"`
+	Hits   []AutomodHit `json:"matches"`
+}
+
+// Messages returns what to tell the author of a rejected post.
+func (v *AutomodVerdict) Messages() []string {
+	messages := []string{}
+	for _, hit := range v.Hits {
+		if hit.Action != AutomodReject {
+			continue
+		}
+		message := hit.Message
+		if message == "" {
+			message = "Your post was rejected by an automatic moderation rule"
+		}
+		if !Contains(messages, message) {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// ValidateAutomodRule checks that the rule can be evaluated and tidies its
+// keywords.
+func ValidateAutomodRule(rule *models.AutomodRule) error {
+	if !Contains(AutomodRuleTypes, rule.Type) {
+		return fmt.Errorf("%w: type must be one of %s", ErrInvalidAutomodRule, strings.Join(AutomodRuleTypes, ", "))
+	}
+	if !Contains(AutomodActions, rule.Action) {
+		return fmt.Errorf("%w: action must be one of %s", ErrInvalidAutomodRule, strings.Join(AutomodActions, ", "))
+	}
+
+	switch rule.Type {
+	case AutomodKeyword:
+		keywords := []string{}
+		for _, keyword := range rule.Keywords {
+			keyword = strings.TrimSpace(keyword)
+			if keyword != "" && !Contains(keywords, keyword) {
+				keywords = append(keywords, keyword)
+			}
+		}
+		if len(keywords) == 0 {
+			return fmt.Errorf("%w: keyword rules need at least one keyword", ErrInvalidAutomodRule)
+		}
+		rule.Keywords = keywords
+	case AutomodRegex:
+		if rule.Pattern == "" {
+			return fmt.Errorf("%w: regex rules need a pattern", ErrInvalidAutomodRule)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidAutomodRule, err)
+		}
+	case AutomodLinkCount:
+		if rule.Threshold < 0 || rule.Threshold != math.Trunc(rule.Threshold) {
+			return fmt.Errorf("%w: link_count rules need a whole number of links as the threshold", ErrInvalidAutomodRule)
+		}
+	case AutomodCapsRatio:
+		if rule.Threshold <= 0 || rule.Threshold > 1 {
+			return fmt.Errorf("%w: caps_ratio rules need a threshold above 0 and at most 1", ErrInvalidAutomodRule)
+		}
+	case AutomodAccountAge:
+		if rule.Threshold <= 0 {
+			return fmt.Errorf("%w: account_age rules need a threshold in hours above 0", ErrInvalidAutomodRule)
+		}
+	}
+
+	return nil
+}
+
+// EvaluateAutomodRules runs the rules against the post in order.
+func EvaluateAutomodRules(rules []models.AutomodRule, post AutomodPost) *AutomodVerdict {
+	verdict := AutomodVerdict{Hits: []AutomodHit{}}
+	strictest := -1
+	now := time.Now()
+
+	for _, rule := range rules {
+		match, ok := matchAutomodRule(&rule, post, now)
+		if !ok {
+			continue
+		}
+
+		if len(match) > automodMatchLength {
+			cut := automodMatchLength
+			for cut > 0 && !utf8.RuneStart(match[cut]) {
+				cut--
+			}
+			match = match[:cut]
+		}
+		verdict.Hits = append(verdict.Hits, AutomodHit{
+			RuleID:  rule.AutomodRuleID,
+			Name:    rule.Name,
+			Action:  rule.Action,
+			Match:   match,
+			Message: rule.Message,
+		})
+
+		for i, action := range AutomodActions {
+			if action == rule.Action && i > strictest {
+				strictest = i
+				verdict.Action = action
+			}
+		}
+	}
+
+	return &verdict
+}
+
+// matchAutomodRule returns what in the post matched the rule.
+func matchAutomodRule(rule *models.AutomodRule, post AutomodPost, now time.Time) (string, bool) {
+	switch rule.Type {
+	case AutomodKeyword:
+		pattern, err := compileAutomodPattern(automodKeywordPattern(rule.Keywords))
+		if err != nil {
+			log.Printf("Error compiling automod rule %d: %v", rule.AutomodRuleID, err)
+			return "", false
+		}
+		if match := pattern.FindString(post.Text); match != "" {
+			return match, true
+		}
+	case AutomodRegex:
+		pattern, err := compileAutomodPattern(rule.Pattern)
+		if err != nil {
+			log.Printf("Error compiling automod rule %d: %v", rule.AutomodRuleID, err)
+			return "", false
+		}
+		if loc := pattern.FindStringIndex(post.Text); loc != nil {
+			return post.Text[loc[0]:loc[1]], true
+		}
+	case AutomodLinkCount:
+		links := linkPattern.FindAllString(post.Text, -1)
+		if float64(len(links)) > rule.Threshold {
+			return fmt.Sprintf("%d links", len(links)), true
+		}
+	case AutomodCapsRatio:
+		letters, capitals := 0, 0
+		for _, r := range post.Text {
+			if unicode.IsLetter(r) {
+				letters++
+				if unicode.IsUpper(r) {
+					capitals++
+				}
+			}
+		}
+		if letters >= automodMinCapsLetters {
+			ratio := float64(capitals) / float64(letters)
+			if ratio >= rule.Threshold {
+				return fmt.Sprintf("%.0f%% capital letters", ratio*100), true
+			}
+		}
+	case AutomodAccountAge:
+		if post.AccountCreatedAt == nil {
+			return "", false
+		}
+		age := now.Sub(*post.AccountCreatedAt)
+		if age < time.Duration(rule.Threshold*float64(time.Hour)) {
+			return fmt.Sprintf("account is %s old", age.Truncate(time.Minute)), true
+		}
+	}
+
+	return "", false
+}
+
+// automodKeywordPattern matches any of the keywords as whole words, ignoring
+// case.
+func automodKeywordPattern(keywords []string) string {
+	quoted := make([]string, len(keywords))
+	for i, keyword := range keywords {
+		quoted[i] = regexp.QuoteMeta(keyword)
+	}
+	return `(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`
+}
+
+func compileAutomodPattern(source string) (*regexp.Regexp, error) {
+	automodPatterns.Lock()
+	defer automodPatterns.Unlock()
+
+	if pattern, ok := automodPatterns.compiled[source]; ok {
+		return pattern, nil
+	}
+
+	pattern, err := regexp.Compile(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(automodPatterns.compiled) >= automodPatternCacheSize {
+		automodPatterns.compiled = map[string]*regexp.Regexp{}
+	}
+	automodPatterns.compiled[source] = pattern
+	return pattern, nil
+}
+
+type AutomodManager struct {
+	db *gorm.DB
+}
+
+func NewAutomodManager(db *gorm.DB) *AutomodManager {
+	return &AutomodManager{db: db}
+}
+
+func (m *AutomodManager) Rules() ([]models.AutomodRule, error) {
+	rules := []models.AutomodRule{}
+	err := m.db.Order("automod_rule_id").Find(&rules).Error
+	return rules, err
+}
+
+// ScopedRules lists the enabled rules that apply in the category, both global
+// and category rules.
+func (m *AutomodManager) ScopedRules(categoryID uint) ([]models.AutomodRule, error) {
+	rules := []models.AutomodRule{}
+	err := m.db.Where("is_enabled = ? AND (category_id IS NULL OR category_id = ?)", true, categoryID).
+		Order("automod_rule_id").
+		Find(&rules).Error
+	return rules, err
+}
+
+// SaveRule validates the rule and creates or updates it.
+func (m *AutomodManager) SaveRule(rule *models.AutomodRule) error {
+	if err := ValidateAutomodRule(rule); err != nil {
+		return err
+	}
+
+	if rule.CategoryID != nil {
+		var count int64
+		if err := m.db.Model(&models.Category{}).Where("category_id = ?", *rule.CategoryID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrUnknownCategory
+		}
+	}
+
+	return m.db.Save(rule).Error
+}
+
+// Check runs the rules that apply to the post.
+func (m *AutomodManager) Check(post AutomodPost) (*AutomodVerdict, error) {
+	rules, err := m.ScopedRules(post.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	return EvaluateAutomodRules(rules, post), nil
+}
+
+// Record logs every rule the post matched. Held and flagged posts are also
+// reported so they show up in the report queue. A zero targetID means the
+// post was rejected and never saved. tx is the transaction that saves the
+// post, so a held post is never left without its report.
+func (m *AutomodManager) Record(tx *gorm.DB, verdict *AutomodVerdict, post AutomodPost, targetID uint) error {
+	var target *uint
+	if targetID != 0 {
+		target = &targetID
+	}
+
+	names := make([]string, 0, len(verdict.Hits))
+	for _, hit := range verdict.Hits {
+		names = append(names, hit.Name)
+		if err := tx.Create(&models.AutomodMatch{
+			RuleID:     hit.RuleID,
+			UserID:     post.UserID,
+			CategoryID: post.CategoryID,
+			TargetType: post.TargetType,
+			TargetID:   target,
+			Action:     hit.Action,
+			Match:      hit.Match,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	if target == nil || (verdict.Action != AutomodHold && verdict.Action != AutomodFlag) {
+		return nil
+	}
+
+	var open int64
+	if err := tx.Model(&models.Report{}).
+		Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status = ?", AutomodReporterID, post.TargetType, targetID, ReportStatusOpen).
+		Count(&open).Error; err != nil {
+		return err
+	}
+	if open > 0 {
+		return nil
+	}
+
+	return tx.Create(&models.Report{
+		ReporterID:   AutomodReporterID,
+		TargetType:   post.TargetType,
+		TargetID:     targetID,
+		TargetUserID: post.UserID,
+		CategoryID:   post.CategoryID,
+		Reason:       ReportReasonAutomod,
+		Note:         strings.Join(names, ", "),
+		Status:       ReportStatusOpen,
+	}).Error
+}
+
+// Hold hides an edited thread or comment that automod held until its report is
+// reviewed. Only visible posts are held, so a pending post still waits for
+// approval, and a held comment leaves its thread's stats until it is released.
+func (m *AutomodManager) Hold(tx *gorm.DB, targetType string, targetID uint) error {
+	if targetType != ReportTargetComment {
+		return tx.Model(&models.Thread{}).
+			Where("thread_id = ? AND visibility = ?", targetID, VisibilityVisible).
+			Update("visibility", VisibilityHeld).Error
+	}
+
+	var comment models.Comment
+	if err := tx.First(&comment, targetID).Error; err != nil {
+		return err
+	}
+
+	result := tx.Model(&comment).Where("visibility = ?", VisibilityVisible).Update("visibility", VisibilityHeld)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	return AdjustCommentCount(tx, comment.ThreadID, -1)
+}
+
+// Release makes a held thread or comment visible. A released comment is
+// counted in its thread's stats then.
+func (m *AutomodManager) Release(targetType string, targetID uint) error {
+	if targetType != ReportTargetComment {
+		return m.db.Model(&models.Thread{}).
+			Where("thread_id = ? AND visibility = ?", targetID, VisibilityHeld).
+			Update("visibility", VisibilityVisible).Error
+	}
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		var comment models.Comment
+		if err := tx.First(&comment, targetID).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&comment).Where("visibility = ?", VisibilityHeld).Update("visibility", VisibilityVisible)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return AdjustCommentCount(tx, comment.ThreadID, 1)
+	})
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/glebarez/sqlite"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// sqliteCommentCount runs the jsonb_set updates of thread stats with SQLite's
+// json_set, keeping the adjustment they were given.
+func sqliteCommentCount(db *gorm.DB) {
+	dest, ok := db.Statement.Dest.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if expr, ok := dest["stats"].(clause.Expr); ok {
+		dest["stats"] = gorm.Expr("json_set(stats, '$.comments', json_extract(stats, '$.comments') + ?)", expr.Vars...)
+	}
+}
+
+func newAutomodTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to an in-memory database gets its own database.
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	// The models use Postgres column types, so only the columns holding and
+	// releasing touch are created.
+	for _, statement := range []string{
+		`CREATE TABLE threads (thread_id INTEGER PRIMARY KEY, visibility TEXT NOT NULL, stats TEXT NOT NULL, updated_at DATETIME)`,
+		`CREATE TABLE comments (comment_id INTEGER PRIMARY KEY, thread_id INTEGER NOT NULL, visibility TEXT NOT NULL, updated_at DATETIME)`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.Callback().Update().Before("gorm:update").Register("test:comment_count", sqliteCommentCount); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestHoldAndReleaseOfEditedComment(t *testing.T) {
+	tests := []struct {
+		visibility     string
+		count          int
+		wantHeld       string
+		wantHeldCount  int
+		wantReleased   string
+		wantFinalCount int
+	}{
+		// A visible comment leaves the count while held and is counted again
+		// once released, so the count ends where it started.
+		{visibility: VisibilityVisible, count: 1, wantHeld: VisibilityHeld, wantHeldCount: 0, wantReleased: VisibilityVisible, wantFinalCount: 1},
+		// Held comments are not counted yet, so holding them again changes nothing.
+		{visibility: VisibilityHeld, count: 0, wantHeld: VisibilityHeld, wantHeldCount: 0, wantReleased: VisibilityVisible, wantFinalCount: 1},
+		// Pending comments still wait for approval, which releasing cannot skip.
+		{visibility: VisibilityPending, count: 0, wantHeld: VisibilityPending, wantHeldCount: 0, wantReleased: VisibilityPending, wantFinalCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.visibility, func(t *testing.T) {
+			db := newAutomodTestDB(t)
+			manager := NewAutomodManager(db)
+
+			if err := db.Exec(`INSERT INTO threads (thread_id, visibility, stats) VALUES (1, ?, json_object('comments', ?))`, VisibilityVisible, tt.count).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := db.Exec(`INSERT INTO comments (comment_id, thread_id, visibility) VALUES (1, 1, ?)`, tt.visibility).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			assertComment := func(step, wantVisibility string, wantCount int) {
+				t.Helper()
+				var comment models.Comment
+				if err := db.First(&comment, 1).Error; err != nil {
+					t.Fatal(err)
+				}
+				var count int
+				if err := db.Raw(`SELECT json_extract(stats, '$.comments') FROM threads WHERE thread_id = 1`).Scan(&count).Error; err != nil {
+					t.Fatal(err)
+				}
+				if comment.Visibility != wantVisibility || count != wantCount {
+					t.Fatalf("after %s: visibility %q with %d comments, want %q with %d", step, comment.Visibility, count, wantVisibility, wantCount)
+				}
+			}
+
+			if err := manager.Hold(db, ReportTargetComment, 1); err != nil {
+				t.Fatal(err)
+			}
+			assertComment("hold", tt.wantHeld, tt.wantHeldCount)
+
+			if err := manager.Release(ReportTargetComment, 1); err != nil {
+				t.Fatal(err)
+			}
+			assertComment("release", tt.wantReleased, tt.wantFinalCount)
+		})
+	}
+}
+
+func TestEvaluateAutomodRulesMatches(t *testing.T) {
+	rules := []models.AutomodRule{
+		{AutomodRuleID: 1, Type: AutomodKeyword, Action: AutomodFlag, Keywords: []string{"spam", "scam"}},
+		{AutomodRuleID: 2, Type: AutomodRegex, Action: AutomodHold, Pattern: `xé+`},
+	}
+
+	tests := []struct {
+		name      string
+		text      string
+		wantRules []uint
+	}{
+		{name: "keyword ignores case", text: "Buy SCAM coins", wantRules: []uint{1}},
+		{name: "keyword needs a whole word", text: "a spammer wrote this", wantRules: nil},
+		{name: "both rules", text: "spam x" + strings.Repeat("é", 150), wantRules: []uint{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict := EvaluateAutomodRules(rules, AutomodPost{Text: tt.text})
+
+			var matched []uint
+			for _, hit := range verdict.Hits {
+				matched = append(matched, hit.RuleID)
+				// Matches are cut to automodMatchLength bytes without
+				// splitting a character.
+				if len(hit.Match) > automodMatchLength || !utf8.ValidString(hit.Match) {
+					t.Fatalf("rule %d match %q is not valid UTF-8 within %d bytes", hit.RuleID, hit.Match, automodMatchLength)
+				}
+			}
+			if len(matched) != len(tt.wantRules) {
+				t.Fatalf("matched rules %v, want %v", matched, tt.wantRules)
+			}
+			for i := range matched {
+				if matched[i] != tt.wantRules[i] {
+					t.Fatalf("matched rules %v, want %v", matched, tt.wantRules)
+				}
+			}
+		})
+	}
+}
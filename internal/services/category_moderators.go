@@ -0,0 +1,125 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrUnknownCategory = errors.New("unknown category")
+
+// categoryPermissions are the permissions category moderators hold inside the
+// categories they moderate.
+var categoryPermissions = []string{
+	PermissionThreadDeleteAny,
+	PermissionCommentDeleteAny,
+	PermissionReportReview,
+	PermissionPostApprove,
+}
+
+type CategoryModeratorInfo struct {
+	CategoryID uint   `json:"-"`
+	UserID     uint   `json:"user_id"`
+	Username   string `json:"username"`
+}
+
+// HasCategoryPermission reports whether the user moderates the category and
+// the permission is one that category moderators hold. Forum-wide permissions
+// are checked with HasPermission.
+func (m *RBACManager) HasCategoryPermission(userID uint, permission string, categoryID uint) (bool, error) {
+	if !Contains(categoryPermissions, permission) {
+		return false, nil
+	}
+
+	var count int64
+	err := m.db.Model(&models.CategoryModerator{}).
+		Where("user_id = ? AND category_id = ?", userID, categoryID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (m *RBACManager) ModeratedCategories(userID uint) ([]uint, error) {
+	categoryIDs := []uint{}
+	err := m.db.Model(&models.CategoryModerator{}).
+		Where("user_id = ?", userID).
+		Order("category_id").
+		Pluck("category_id", &categoryIDs).Error
+	return categoryIDs, err
+}
+
+// SetModeratedCategories replaces the categories a user moderates. It leaves
+// their role level alone, since category moderators are not global staff.
+func (m *RBACManager) SetModeratedCategories(user *models.User, categoryIDs []uint, assignedBy uint) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		unique := map[uint]bool{}
+		for _, categoryID := range categoryIDs {
+			unique[categoryID] = true
+		}
+
+		if len(unique) > 0 {
+			var count int64
+			if err := tx.Model(&models.Category{}).Where("category_id IN ?", categoryIDs).Count(&count).Error; err != nil {
+				return err
+			}
+			if int(count) != len(unique) {
+				return ErrUnknownCategory
+			}
+		}
+
+		var existing []models.CategoryModerator
+		if err := tx.Where("user_id = ?", user.UserID).Find(&existing).Error; err != nil {
+			return err
+		}
+
+		for _, assignment := range existing {
+			if unique[assignment.CategoryID] {
+				delete(unique, assignment.CategoryID)
+				continue
+			}
+			if err := tx.Delete(&assignment).Error; err != nil {
+				return err
+			}
+		}
+
+		for categoryID := range unique {
+			assignment := models.CategoryModerator{UserID: user.UserID, CategoryID: categoryID, AssignedBy: assignedBy}
+			if err := tx.Create(&assignment).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// CategoryModerators lists the moderators of every category, or of one
+// category when categoryID is not zero.
+func (m *RBACManager) CategoryModerators(categoryID uint) ([]CategoryModeratorInfo, error) {
+	query := m.db.Model(&models.CategoryModerator{}).
+		Select("category_moderators.category_id, users.user_id, users.username").
+		Joins("JOIN users ON users.user_id = category_moderators.user_id").
+		Where("users.is_deleted = ?", false)
+	if categoryID != 0 {
+		query = query.Where("category_moderators.category_id = ?", categoryID)
+	}
+
+	var moderators []CategoryModeratorInfo
+	err := query.Order("users.username").Scan(&moderators).Error
+	return moderators, err
+}
+
+// ForumModerators lists the users who can moderate every category through
+// their roles.
+func (m *RBACManager) ForumModerators() ([]CategoryModeratorInfo, error) {
+	moderators := []CategoryModeratorInfo{}
+	err := m.db.Model(&models.User{}).
+		Distinct("users.user_id", "users.username").
+		Joins("JOIN user_roles ON user_roles.user_id = users.user_id").
+		Joins("JOIN role_permissions ON role_permissions.role_id = user_roles.role_id").
+		Joins("JOIN permissions ON permissions.permission_id = role_permissions.permission_id").
+		Where("permissions.name = ? AND users.is_deleted = ?", PermissionThreadDeleteAny, false).
+		Order("users.username").
+		Scan(&moderators).Error
+	return moderators, err
+}
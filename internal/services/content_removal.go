@@ -0,0 +1,49 @@
+package services
+
+import (
+	"time"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+)
+
+// Reasons stored on removed threads and comments, so content can be told
+// apart by how it was removed.
+const (
+	RemovedByAuthor     = "author"
+	RemovedByModerator  = "moderator"
+	RemovedBySuspension = "suspension"
+)
+
+type RemovedContent struct {
+	Threads  []models.Thread  `json:"threads"`
+	Comments []models.Comment `json:"comments"`
+}
+
+// RemovalColumns marks a thread or comment as removed by the actor. The
+// suspension is only set for content removed along with its author.
+func RemovalColumns(reason string, actorID uint, suspensionID *uint) map[string]interface{} {
+	return map[string]interface{}{
+		"is_deleted":     true,
+		"removal_reason": reason,
+		"removed_by":     actorID,
+		"removed_at":     time.Now(),
+		"suspension_id":  suspensionID,
+	}
+}
+
+func restoreColumns() map[string]interface{} {
+	return map[string]interface{}{
+		"is_deleted":     false,
+		"removal_reason": "",
+		"removed_by":     nil,
+		"removed_at":     nil,
+		"suspension_id":  nil,
+	}
+}
+
+// suspendedContent selects the content of the user that was removed by a
+// suspension and is still removed.
+func suspendedContent(db *gorm.DB, model interface{}, userID uint) *gorm.DB {
+	return db.Model(model).Where("user_id = ? AND is_deleted = ? AND removal_reason = ?", userID, true, RemovedBySuspension)
+}
@@ -26,3 +26,8 @@ func SetCookie(c *gin.Context, name, value string, maxAge int) {
 		true, // HTTPOnly flag
 	)
 }
+
+func ClearAuthCookies(c *gin.Context) {
+	SetCookie(c, "refresh_token", "", -1)
+	SetCookie(c, "access_token", "", -1)
+}
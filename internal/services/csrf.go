@@ -0,0 +1,41 @@
+package services
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CSRFCookie = "csrf_token"
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// CSRFToken returns the token from the CSRF cookie, issuing a new one when
+// the browser does not have it yet. The frontend echoes it back in the
+// X-CSRF-Token header, which a cross-site form or script cannot do.
+func CSRFToken(c *gin.Context) (string, error) {
+	if token, err := c.Cookie(CSRFCookie); err == nil && len(token) == 64 {
+		return token, nil
+	}
+
+	token, err := RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	SetCookie(c, CSRFCookie, token, int(RefreshTokenDuration.Seconds()))
+	return token, nil
+}
+
+// ValidCSRFToken reports whether the request carries the same token in the
+// CSRF cookie and header.
+func ValidCSRFToken(c *gin.Context) bool {
+	cookie, err := c.Cookie(CSRFCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+
+	header := c.GetHeader(CSRFHeader)
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
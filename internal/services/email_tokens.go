@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	EmailTokenVerifyEmail   = "verify_email"
+	EmailTokenResetPassword = "reset_password"
+	EmailTokenMagicLink     = "magic_link"
+
+	VerifyEmailTokenDuration   = 24 * time.Hour
+	ResetPasswordTokenDuration = 30 * time.Minute
+	MagicLinkTokenDuration     = 15 * time.Minute
+)
+
+var ErrInvalidEmailToken = errors.New("invalid or expired token")
+
+// EmailTokenManager issues the single-use tokens sent in email links. Only a
+// digest of each token is stored.
+type EmailTokenManager struct {
+	db *gorm.DB
+}
+
+func NewEmailTokenManager(db *gorm.DB) *EmailTokenManager {
+	return &EmailTokenManager{db: db}
+}
+
+// Issue creates a new token and invalidates the unused ones the user already
+// had for the same purpose.
+func (m *EmailTokenManager) Issue(userID uint, purpose, email string, duration time.Duration) (string, error) {
+	token, err := RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.EmailToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&models.EmailToken{
+			UserID:    userID,
+			Purpose:   purpose,
+			Email:     email,
+			TokenHash: HashToken(token),
+			ExpiresAt: time.Now().Add(duration),
+		}).Error
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Peek returns the token without using it up, so a request can be validated
+// before the token is consumed.
+func (m *EmailTokenManager) Peek(token, purpose string) (*models.EmailToken, error) {
+	var emailToken models.EmailToken
+	if err := m.db.Where("token_hash = ? AND purpose = ?", HashToken(token), purpose).First(&emailToken).Error; err != nil {
+		return nil, ErrInvalidEmailToken
+	}
+
+	if emailToken.UsedAt != nil || emailToken.ExpiresAt.Before(time.Now()) {
+		return nil, ErrInvalidEmailToken
+	}
+
+	return &emailToken, nil
+}
+
+// Consume marks the token as used and returns it, failing if it is unknown,
+// expired, already used or issued for another purpose.
+func (m *EmailTokenManager) Consume(token, purpose string) (*models.EmailToken, error) {
+	emailToken, err := m.Peek(token, purpose)
+	if err != nil {
+		return nil, err
+	}
+
+	result := m.db.Model(&models.EmailToken{}).
+		Where("email_token_id = ? AND used_at IS NULL", emailToken.EmailTokenID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInvalidEmailToken
+	}
+
+	return emailToken, nil
+}
+
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// FrontendLink builds a link to a frontend page, resolved against
+// FRONTEND_REDIRECT_URL.
+func FrontendLink(path string, query url.Values) string {
+	base, err := url.Parse(os.Getenv("FRONTEND_REDIRECT_URL"))
+	if err != nil {
+		base = &url.URL{}
+	}
+
+	link := base.ResolveReference(&url.URL{Path: path})
+	link.RawQuery = query.Encode()
+	return link.String()
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	ImpersonationTokenType = "impersonation"
+
+	DefaultImpersonationDuration = 30 * time.Minute
+	MaxImpersonationDuration     = time.Hour
+)
+
+var (
+	ErrInvalidImpersonation = errors.New("invalid or expired impersonation token")
+	ErrImpersonationDenied  = errors.New("user cannot be impersonated")
+)
+
+// ImpersonationClaims name both the admin and the user they act as, so every
+// request made with the token can be traced back to the admin.
+type ImpersonationClaims struct {
+	UserID          uint   `json:"user_id"`
+	ImpersonatorID  uint   `json:"impersonator_id"`
+	ImpersonationID uint   `json:"impersonation_id"`
+	TokenType       string `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+type ImpersonationManager struct {
+	db *gorm.DB
+}
+
+func NewImpersonationManager(db *gorm.DB) *ImpersonationManager {
+	return &ImpersonationManager{db: db}
+}
+
+// Start records the impersonation and returns the token the admin sends as
+// an Authorization: Bearer header. Only users with a lower role can be
+// impersonated.
+func (m *ImpersonationManager) Start(admin, target *models.User, reason string, duration time.Duration) (string, *models.Impersonation, error) {
+	outranks, err := NewRBACManager(m.db).Outranks(admin, target)
+	if err != nil {
+		return "", nil, err
+	}
+	if !outranks || target.IsDeleted {
+		return "", nil, ErrImpersonationDenied
+	}
+
+	now := time.Now()
+	impersonation := &models.Impersonation{
+		AdminID:      admin.UserID,
+		TargetUserID: target.UserID,
+		Reason:       reason,
+		ExpiresAt:    now.Add(duration),
+	}
+	if err := m.db.Create(impersonation).Error; err != nil {
+		return "", nil, err
+	}
+
+	token, err := SignClaims(ImpersonationClaims{
+		UserID:          target.UserID,
+		ImpersonatorID:  admin.UserID,
+		ImpersonationID: impersonation.ImpersonationID,
+		TokenType:       ImpersonationTokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(impersonation.ExpiresAt),
+		},
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return token, impersonation, nil
+}
+
+// Authenticate checks an impersonation token and returns the impersonation
+// and the impersonated user. The token stops working once the impersonation
+// is ended or the admin loses their role.
+func (m *ImpersonationManager) Authenticate(token string) (*models.Impersonation, *models.User, error) {
+	claims := &ImpersonationClaims{}
+	if err := ParseClaims(token, claims); err != nil || claims.TokenType != ImpersonationTokenType {
+		return nil, nil, ErrInvalidImpersonation
+	}
+
+	var impersonation models.Impersonation
+	if err := m.db.First(&impersonation, claims.ImpersonationID).Error; err != nil {
+		return nil, nil, ErrInvalidImpersonation
+	}
+
+	if impersonation.EndedAt != nil || impersonation.ExpiresAt.Before(time.Now()) ||
+		impersonation.AdminID != claims.ImpersonatorID || impersonation.TargetUserID != claims.UserID {
+		return nil, nil, ErrInvalidImpersonation
+	}
+
+	var admin models.User
+	if err := m.db.First(&admin, impersonation.AdminID).Error; err != nil || admin.IsBanned || admin.IsDeleted {
+		return nil, nil, ErrInvalidImpersonation
+	}
+
+	if allowed, err := NewRBACManager(m.db).HasPermission(admin.UserID, PermissionUserImpersonate); err != nil || !allowed {
+		return nil, nil, ErrInvalidImpersonation
+	}
+
+	var target models.User
+	if err := m.db.First(&target, impersonation.TargetUserID).Error; err != nil || target.IsDeleted {
+		return nil, nil, ErrInvalidImpersonation
+	}
+
+	return &impersonation, &target, nil
+}
+
+func (m *ImpersonationManager) End(impersonationID uint) error {
+	return m.db.Model(&models.Impersonation{}).
+		Where("impersonation_id = ? AND ended_at IS NULL", impersonationID).
+		Update("ended_at", time.Now()).Error
+}
+
+// RecordRequest appends a request made while impersonating to the audit log
+// before it is handled, so a request the log cannot hold is never served. The
+// status code is filled in by RecordStatus afterwards.
+func (m *ImpersonationManager) RecordRequest(c *gin.Context, impersonation *models.Impersonation) (*models.ImpersonationLog, error) {
+	entry := &models.ImpersonationLog{
+		ImpersonationID: impersonation.ImpersonationID,
+		AdminID:         impersonation.AdminID,
+		TargetUserID:    impersonation.TargetUserID,
+		Method:          c.Request.Method,
+		Path:            c.Request.URL.RequestURI(),
+		IPAddress:       c.ClientIP(),
+		UserAgent:       c.Request.UserAgent(),
+	}
+	if err := m.db.Create(entry).Error; err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (m *ImpersonationManager) RecordStatus(entry *models.ImpersonationLog, statusCode int) error {
+	return m.db.Model(entry).Update("status_code", statusCode).Error
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrNoSigningKey = errors.New("no JWT signing key configured, set JWT_SIGNING_KEY_FILE or JWT_SECRET")
+
+// SigningKey is one key of the key ring. Keys without a private part, or
+// with AcceptUntil set, only verify tokens signed before a rotation.
+type SigningKey struct {
+	ID          string
+	Method      jwt.SigningMethod
+	SignKey     interface{}
+	VerifyKey   interface{}
+	AcceptUntil time.Time
+}
+
+func (k *SigningKey) acceptsAt(now time.Time) bool {
+	return k.AcceptUntil.IsZero() || now.Before(k.AcceptUntil)
+}
+
+// KeyRing signs tokens with its active key and verifies them with whichever
+// key the kid header names, so keys can be rotated without logging everyone
+// out. Tokens issued before kid headers were added are tried against every
+// HS256 secret that is still accepted.
+type KeyRing struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+	order  []string
+}
+
+var (
+	defaultKeyRing     *KeyRing
+	defaultKeyRingErr  error
+	defaultKeyRingOnce sync.Once
+)
+
+// DefaultKeyRing loads the key ring from the environment on first use.
+func DefaultKeyRing() (*KeyRing, error) {
+	defaultKeyRingOnce.Do(func() {
+		defaultKeyRing, defaultKeyRingErr = LoadKeyRingFromEnv()
+	})
+	return defaultKeyRing, defaultKeyRingErr
+}
+
+// LoadKeyRingFromEnv builds the key ring from:
+//   - JWT_SIGNING_KEY_FILE: PEM encoded RSA or Ed25519 private key used to sign
+//     tokens with RS256 or EdDSA. JWT_SIGNING_KEY_ID overrides its kid.
+//   - JWT_SECRET: HS256 secret. It signs tokens when no key file is set and
+//     otherwise only verifies tokens issued before the switch.
+//   - JWT_VERIFICATION_KEYS: comma separated PEM files of retired keys, each
+//     optionally followed by @RFC3339 to stop accepting it after that time.
+//   - JWT_PREVIOUS_SECRETS: comma separated retired HS256 secrets, with the
+//     same optional @RFC3339 suffix.
+func LoadKeyRingFromEnv() (*KeyRing, error) {
+	ring := &KeyRing{keys: map[string]*SigningKey{}}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		key := hmacKey(secret, time.Time{})
+		ring.add(key)
+		ring.active = key
+	}
+
+	if path := os.Getenv("JWT_SIGNING_KEY_FILE"); path != "" {
+		key, err := loadPEMKey(path, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		if key.SignKey == nil {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY_FILE %s does not contain a private key", path)
+		}
+		if id := os.Getenv("JWT_SIGNING_KEY_ID"); id != "" {
+			key.ID = id
+		}
+		ring.add(key)
+		ring.active = key
+	}
+
+	for _, entry := range splitList(os.Getenv("JWT_VERIFICATION_KEYS")) {
+		path, acceptUntil, err := parseKeyEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		key, err := loadPEMKey(path, acceptUntil)
+		if err != nil {
+			return nil, err
+		}
+		key.SignKey = nil
+		ring.add(key)
+	}
+
+	for _, entry := range splitList(os.Getenv("JWT_PREVIOUS_SECRETS")) {
+		secret, acceptUntil, err := parseKeyEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		key := hmacKey(secret, acceptUntil)
+		key.SignKey = nil
+		ring.add(key)
+	}
+
+	if ring.active == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	return ring, nil
+}
+
+func (r *KeyRing) add(key *SigningKey) {
+	if _, exists := r.keys[key.ID]; !exists {
+		r.keys[key.ID] = key
+		r.order = append(r.order, key.ID)
+	}
+}
+
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(r.active.Method, claims)
+	token.Header["kid"] = r.active.ID
+	return token.SignedString(r.active.SignKey)
+}
+
+func (r *KeyRing) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		now := time.Now()
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			return r.legacyKeys(token, now)
+		}
+
+		key := r.keys[kid]
+		if key == nil || !key.acceptsAt(now) {
+			return nil, errors.New("unknown or retired signing key")
+		}
+
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, errors.New("unexpected signing method")
+		}
+
+		return key.VerifyKey, nil
+	}, jwt.WithValidMethods([]string{
+		jwt.SigningMethodHS256.Alg(),
+		jwt.SigningMethodRS256.Alg(),
+		jwt.SigningMethodEdDSA.Alg(),
+	}))
+}
+
+// legacyKeys returns the HS256 secrets a token without a kid may have been
+// signed with, the current JWT_SECRET and any previous secret still accepted.
+func (r *KeyRing) legacyKeys(token *jwt.Token, now time.Time) (interface{}, error) {
+	if token.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	keySet := jwt.VerificationKeySet{}
+	for _, id := range r.order {
+		key := r.keys[id]
+		if key.Method.Alg() == jwt.SigningMethodHS256.Alg() && key.acceptsAt(now) {
+			keySet.Keys = append(keySet.Keys, key.VerifyKey)
+		}
+	}
+
+	if len(keySet.Keys) == 0 {
+		return nil, errors.New("unknown or retired signing key")
+	}
+	return keySet, nil
+}
+
+// JWK is a public key in the JSON Web Key format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS lists the public keys other services can verify tokens with. HS256
+// secrets are never published.
+func (r *KeyRing) JWKS() []JWK {
+	now := time.Now()
+	keys := []JWK{}
+	for _, id := range r.order {
+		key := r.keys[id]
+		if !key.acceptsAt(now) {
+			continue
+		}
+
+		switch publicKey := key.VerifyKey.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.ID,
+				Use:       "sig",
+				Algorithm: key.Method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		}
+	}
+	return keys
+}
+
+func hmacKey(secret string, acceptUntil time.Time) *SigningKey {
+	sum := sha256.Sum256([]byte(secret))
+	return &SigningKey{
+		ID:          "hs256-" + hex.EncodeToString(sum[:6]),
+		Method:      jwt.SigningMethodHS256,
+		SignKey:     []byte(secret),
+		VerifyKey:   []byte(secret),
+		AcceptUntil: acceptUntil,
+	}
+}
+
+// loadPEMKey reads an RSA or Ed25519 key, either a private key in PKCS#8 or
+// PKCS#1 form or a public key in PKIX form.
+func loadPEMKey(path string, acceptUntil time.Time) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key file %s is not PEM encoded", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key file %s: %w", path, err)
+	}
+
+	key := &SigningKey{AcceptUntil: acceptUntil}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.SignKey, key.VerifyKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.VerifyKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.SignKey, key.VerifyKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.VerifyKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("key file %s must hold an RSA or Ed25519 key", path)
+	}
+
+	key.ID, err = publicKeyID(key.VerifyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// publicKeyID derives a stable kid from the public key, so the same file
+// always gets the same kid.
+func publicKeyID(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
+
+func parseKeyEntry(entry string) (string, time.Time, error) {
+	value, until, found := strings.Cut(entry, "@")
+	if !found {
+		return value, time.Time{}, nil
+	}
+
+	acceptUntil, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid accept-until time %q: %w", until, err)
+	}
+	return value, acceptUntil, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signWithoutKid(t *testing.T, secret string) string {
+	t.Helper()
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "1",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestKeyRingParsesTokensWithoutKid(t *testing.T) {
+	t.Setenv("JWT_SECRET", "current")
+	t.Setenv("JWT_SIGNING_KEY_FILE", "")
+	t.Setenv("JWT_VERIFICATION_KEYS", "")
+	t.Setenv("JWT_PREVIOUS_SECRETS", "previous@"+time.Now().Add(time.Hour).Format(time.RFC3339)+
+		",retired@"+time.Now().Add(-time.Hour).Format(time.RFC3339))
+
+	ring, err := LoadKeyRingFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		secret string
+		valid  bool
+	}{
+		{secret: "current", valid: true},
+		{secret: "previous", valid: true},
+		{secret: "retired", valid: false},
+		{secret: "unknown", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.secret, func(t *testing.T) {
+			_, err := ring.Parse(signWithoutKid(t, tt.secret), &jwt.RegisteredClaims{})
+			if (err == nil) != tt.valid {
+				t.Fatalf("Parse error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// freeLoginFailures is how many wrong passwords an account tolerates
+	// before it is locked.
+	freeLoginFailures = 5
+	baseLockout       = 30 * time.Second
+	maxLockout        = time.Hour
+)
+
+// LoginThrottle locks an account for exponentially longer periods after
+// repeated failed logins, so passwords cannot be guessed one bcrypt
+// comparison after another.
+type LoginThrottle struct {
+	store AttemptStore
+}
+
+func NewLoginThrottle(store AttemptStore) *LoginThrottle {
+	return &LoginThrottle{store: store}
+}
+
+// RetryAfter returns how long the account stays locked, or zero when a new
+// attempt is allowed.
+func (t *LoginThrottle) RetryAfter(account string) (time.Duration, error) {
+	failures, last, err := t.store.Failures(accountKey(account))
+	if err != nil {
+		return 0, err
+	}
+
+	if failures < freeLoginFailures {
+		return 0, nil
+	}
+
+	retryAfter := time.Until(last.Add(lockoutDuration(failures)))
+	if retryAfter < 0 {
+		return 0, nil
+	}
+	return retryAfter, nil
+}
+
+func (t *LoginThrottle) RecordFailure(account string) error {
+	_, err := t.store.AddFailure(accountKey(account), time.Now())
+	return err
+}
+
+func (t *LoginThrottle) RecordSuccess(account string) error {
+	return t.store.ResetFailures(accountKey(account))
+}
+
+func lockoutDuration(failures int) time.Duration {
+	exponent := float64(failures - freeLoginFailures)
+	duration := time.Duration(float64(baseLockout) * math.Pow(2, exponent))
+	if duration > maxLockout || duration <= 0 {
+		return maxLockout
+	}
+	return duration
+}
+
+func accountKey(account string) string {
+	return "account:" + strings.ToLower(account)
+}
+
+// RetryAfterSeconds rounds a wait up to whole seconds for the Retry-After
+// header.
+func RetryAfterSeconds(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional mail such as verification and password reset
+// links. Which implementation is used depends on MAIL_DRIVER.
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
+
+var mailDrivers = []string{"smtp", "file", "log"}
+
+// ValidateMailerEnv checks MAIL_DRIVER at startup. The log driver prints
+// sign-in and password reset links, so it is only the default when
+// GO_ENVIRONMENT is development and must be chosen explicitly elsewhere.
+func ValidateMailerEnv() error {
+	driver := os.Getenv("MAIL_DRIVER")
+	if driver == "" {
+		if os.Getenv("GO_ENVIRONMENT") != "development" {
+			return errors.New("MAIL_DRIVER must be set outside development")
+		}
+		return nil
+	}
+
+	if !Contains(mailDrivers, driver) {
+		return fmt.Errorf("unknown MAIL_DRIVER %q, must be one of %s", driver, strings.Join(mailDrivers, ", "))
+	}
+
+	if driver == "log" && os.Getenv("GO_ENVIRONMENT") == "production" {
+		return errors.New("MAIL_DRIVER log would print sign-in links to the production log")
+	}
+
+	if driver == "smtp" && (os.Getenv("SMTP_HOST") == "" || os.Getenv("SMTP_PORT") == "") {
+		return errors.New("MAIL_DRIVER smtp needs SMTP_HOST and SMTP_PORT")
+	}
+
+	return nil
+}
+
+// NewMailerFromEnv picks a mailer from MAIL_DRIVER: "smtp", "file" (writes to
+// MAIL_OUTBOX_DIR) or "log", which is the default in development so it never
+// needs a mail server. ValidateMailerEnv rejects a missing driver elsewhere.
+func NewMailerFromEnv() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@olympliance.local"
+	}
+
+	switch os.Getenv("MAIL_DRIVER") {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	case "file":
+		dir := os.Getenv("MAIL_OUTBOX_DIR")
+		if dir == "" {
+			dir = "outbox"
+		}
+		return &FileMailer{Dir: dir, From: from}
+	default:
+		return &LogMailer{From: from}
+	}
+}
+
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, mail Mail) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, m.From, []string{mail.To}, formatMail(m.From, mail))
+}
+
+// FileMailer writes every message to its own .eml file, which is handy for
+// local development and for tests that need to read the links back.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *FileMailer) Send(ctx context.Context, mail Mail) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	suffix, err := RandomToken(4)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), suffix)
+	return os.WriteFile(filepath.Join(m.Dir, name), formatMail(m.From, mail), 0o600)
+}
+
+type LogMailer struct {
+	From string
+}
+
+func (m *LogMailer) Send(ctx context.Context, mail Mail) error {
+	log.Printf("Mail to %s: %s\n%s", mail.To, mail.Subject, mail.Body)
+	return nil
+}
+
+func formatMail(from string, mail Mail) []byte {
+	headers := []string{
+		"From: " + from,
+		"To: " + mail.To,
+		"Subject: " + mail.Subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(mail.Body, "\n", "\r\n"))
+}
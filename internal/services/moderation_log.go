@@ -0,0 +1,85 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	ModerationSuspendUser     = "user.suspend"
+	ModerationLiftSuspension  = "user.unsuspend"
+	ModerationAssignModerator = "moderator.assign"
+	ModerationRemoveModerator = "moderator.remove"
+	ModerationSetCategories   = "moderator.categories"
+	ModerationSetRoles        = "user.roles"
+	ModerationDeleteThread    = "thread.delete"
+	ModerationDeleteComment   = "comment.delete"
+	ModerationResolveReports  = "report.resolve"
+	ModerationCreateRule      = "automod.create"
+	ModerationUpdateRule      = "automod.update"
+	ModerationDeleteRule      = "automod.delete"
+	ModerationApprovePost     = "post.approve"
+)
+
+const (
+	ModerationTargetUser    = "user"
+	ModerationTargetThread  = "thread"
+	ModerationTargetComment = "comment"
+	ModerationTargetRule    = "automod_rule"
+)
+
+var ModerationActions = []string{
+	ModerationSuspendUser,
+	ModerationLiftSuspension,
+	ModerationAssignModerator,
+	ModerationRemoveModerator,
+	ModerationSetCategories,
+	ModerationSetRoles,
+	ModerationDeleteThread,
+	ModerationDeleteComment,
+	ModerationResolveReports,
+	ModerationCreateRule,
+	ModerationUpdateRule,
+	ModerationDeleteRule,
+	ModerationApprovePost,
+}
+
+type ModerationEntry struct {
+	Action       string
+	TargetType   string
+	TargetID     uint
+	TargetUserID uint
+	Reason       string
+	Before       interface{}
+	After        interface{}
+}
+
+// RecordModeration appends a staff action to moderation_logs, which the
+// database refuses to update or delete, with JSON snapshots of the target
+// before and after it. tx is the transaction that takes the action, so the
+// action and its entry are committed or rolled back together.
+func RecordModeration(tx *gorm.DB, actor *models.User, entry ModerationEntry) error {
+	before, err := json.Marshal(entry.Before)
+	if err != nil {
+		return fmt.Errorf("failed to encode moderation snapshot for %s: %w", entry.Action, err)
+	}
+
+	after, err := json.Marshal(entry.After)
+	if err != nil {
+		return fmt.Errorf("failed to encode moderation snapshot for %s: %w", entry.Action, err)
+	}
+
+	return tx.Create(&models.ModerationLog{
+		ActorID:      actor.UserID,
+		Action:       entry.Action,
+		TargetType:   entry.TargetType,
+		TargetID:     entry.TargetID,
+		TargetUserID: entry.TargetUserID,
+		Reason:       entry.Reason,
+		Before:       before,
+		After:        after,
+	}).Error
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
+	"golang.org/x/oauth2/google"
+)
+
+var ErrOAuthProviderNotFound = errors.New("oauth provider not found")
+
+var oauthHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// OAuthUserInfo is the part of a provider's user profile the app cares about.
+type OAuthUserInfo struct {
+	Subject string
+	Name    string
+	Email   string
+}
+
+// OAuthProvider is a sign-in provider reachable through
+// /api/auth/:provider/ and /api/auth/:provider/callback.
+type OAuthProvider interface {
+	Name() string
+	AuthCodeURL(state *OAuthState) string
+	Exchange(ctx context.Context, code string, state *OAuthState) (*OAuthUserInfo, error)
+}
+
+type OAuthProviderRegistry struct {
+	providers map[string]OAuthProvider
+}
+
+func NewOAuthProviderRegistry() *OAuthProviderRegistry {
+	return &OAuthProviderRegistry{providers: map[string]OAuthProvider{}}
+}
+
+func (r *OAuthProviderRegistry) Register(provider OAuthProvider) {
+	r.providers[provider.Name()] = provider
+}
+
+func (r *OAuthProviderRegistry) Get(name string) (OAuthProvider, error) {
+	provider, exists := r.providers[strings.ToLower(name)]
+	if !exists {
+		return nil, ErrOAuthProviderNotFound
+	}
+	return provider, nil
+}
+
+// LoadOAuthProviders registers every provider whose client ID is present in
+// the environment. Generic OIDC providers are listed in OIDC_PROVIDERS and
+// configured from their discovery documents, e.g. OIDC_PROVIDERS=partner with
+// OIDC_PARTNER_ISSUER_URL, OIDC_PARTNER_CLIENT_ID, OIDC_PARTNER_CLIENT_SECRET
+// and OIDC_PARTNER_REDIRECT_URL.
+func LoadOAuthProviders(ctx context.Context) *OAuthProviderRegistry {
+	registry := NewOAuthProviderRegistry()
+
+	if clientID := os.Getenv("GOOGLE_CLIENT_ID"); clientID != "" {
+		registry.Register(&OIDCProvider{
+			name:        "google",
+			issuer:      "https://accounts.google.com",
+			userInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+			keys:        newOIDCKeySet("https://www.googleapis.com/oauth2/v3/certs"),
+			config: oauth2.Config{
+				ClientID:     clientID,
+				ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+				RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
+				Scopes:       []string{"openid", "profile", "email"},
+				Endpoint:     google.Endpoint,
+			},
+		})
+	}
+
+	if clientID := os.Getenv("GITHUB_CLIENT_ID"); clientID != "" {
+		registry.Register(&GitHubProvider{
+			apiURL: "https://api.github.com",
+			config: oauth2.Config{
+				ClientID:     clientID,
+				ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
+				RedirectURL:  os.Getenv("GITHUB_REDIRECT_URL"),
+				Scopes:       []string{"read:user", "user:email"},
+				Endpoint:     endpoints.GitHub,
+			},
+		})
+	}
+
+	if clientID := os.Getenv("MICROSOFT_CLIENT_ID"); clientID != "" {
+		tenant := os.Getenv("MICROSOFT_TENANT")
+		if tenant == "" {
+			tenant = "common"
+		}
+		registerOIDCProvider(ctx, registry, "microsoft", "https://login.microsoftonline.com/"+tenant+"/v2.0", oauth2.Config{
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("MICROSOFT_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("MICROSOFT_REDIRECT_URL"),
+		})
+	}
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		config := oauth2.Config{
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		}
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			config.Scopes = strings.Split(scopes, ",")
+		}
+		registerOIDCProvider(ctx, registry, name, os.Getenv(prefix+"ISSUER_URL"), config)
+	}
+
+	return registry
+}
+
+func registerOIDCProvider(ctx context.Context, registry *OAuthProviderRegistry, name, issuerURL string, config oauth2.Config) {
+	provider, err := NewOIDCProvider(ctx, name, issuerURL, config)
+	if err != nil {
+		log.Printf("Warning: OAuth provider %s disabled: %v", name, err)
+		return
+	}
+	registry.Register(provider)
+}
+
+// oidcClockSkew is how far the provider's clock may drift from ours when
+// checking the exp and iat claims of an ID token.
+const oidcClockSkew = time.Minute
+
+// OIDCProvider signs users in with OpenID Connect. The ID token's signature
+// is checked against the keys published at the provider's jwks_uri, along
+// with its issuer, audience, nonce, expiry and issue time.
+type OIDCProvider struct {
+	name        string
+	issuer      string
+	userInfoURL string
+	keys        *oidcKeySet
+	config      oauth2.Config
+}
+
+type oidcDiscoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewOIDCProvider configures a provider from the discovery document
+// published under issuerURL.
+func NewOIDCProvider(ctx context.Context, name, issuerURL string, config oauth2.Config) (*OIDCProvider, error) {
+	if issuerURL == "" || config.ClientID == "" {
+		return nil, errors.New("issuer URL and client ID are required")
+	}
+
+	discoveryURL := strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration"
+	var document oidcDiscoveryDocument
+	if err := getJSON(ctx, oauthHTTPClient, discoveryURL, &document); err != nil {
+		return nil, fmt.Errorf("failed to fetch discovery document: %w", err)
+	}
+
+	if document.AuthorizationEndpoint == "" || document.TokenEndpoint == "" || document.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	config.Endpoint = oauth2.Endpoint{
+		AuthURL:  document.AuthorizationEndpoint,
+		TokenURL: document.TokenEndpoint,
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "profile", "email"}
+	}
+
+	return &OIDCProvider{
+		name:        name,
+		issuer:      document.Issuer,
+		userInfoURL: document.UserInfoEndpoint,
+		keys:        newOIDCKeySet(document.JWKSURI),
+		config:      config,
+	}, nil
+}
+
+func (p *OIDCProvider) Name() string {
+	return p.name
+}
+
+func (p *OIDCProvider) AuthCodeURL(state *OAuthState) string {
+	return p.config.AuthCodeURL(
+		state.State,
+		oauth2.S256ChallengeOption(state.Verifier),
+		oauth2.SetAuthURLParam("nonce", state.Nonce),
+	)
+}
+
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, state *OAuthState) (*OAuthUserInfo, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, oauthHTTPClient)
+
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange token: %w", err)
+	}
+
+	claims, err := p.verifyIDToken(ctx, token, state.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &OAuthUserInfo{}
+	info.Subject, _ = claims.GetSubject()
+	info.Name, _ = claims["name"].(string)
+	info.Email, _ = claims["email"].(string)
+
+	if p.userInfoURL != "" {
+		var userInfo struct {
+			Sub   string `json:"sub"`
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		}
+		if err := getJSON(ctx, p.config.Client(ctx, token), p.userInfoURL, &userInfo); err != nil {
+			return nil, fmt.Errorf("failed to get user info: %w", err)
+		}
+		if userInfo.Sub != info.Subject {
+			return nil, errors.New("user info does not match ID token")
+		}
+		if userInfo.Name != "" {
+			info.Name = userInfo.Name
+		}
+		if userInfo.Email != "" {
+			info.Email = userInfo.Email
+		}
+	}
+
+	if info.Subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+
+	return info, nil
+}
+
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, token *oauth2.Token, nonce string) (jwt.MapClaims, error) {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("id_token missing from token response")
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keys.Key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodRS384.Alg(),
+			jwt.SigningMethodRS512.Alg(),
+			jwt.SigningMethodPS256.Alg(),
+			jwt.SigningMethodES256.Alg(),
+			jwt.SigningMethodES384.Alg(),
+			jwt.SigningMethodEdDSA.Alg(),
+		}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(oidcClockSkew),
+	); err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	if issuedAt, err := claims.GetIssuedAt(); err != nil || issuedAt == nil {
+		return nil, errors.New("ID token has no issue time")
+	}
+
+	if claimedNonce, _ := claims["nonce"].(string); subtle.ConstantTimeCompare([]byte(claimedNonce), []byte(nonce)) != 1 {
+		return nil, errors.New("nonce mismatch")
+	}
+
+	audience, err := claims.GetAudience()
+	if err != nil || !Contains(audience, p.config.ClientID) {
+		return nil, errors.New("audience mismatch")
+	}
+
+	// Multi-tenant issuers such as Microsoft's "common" endpoint advertise a
+	// {tenantid} placeholder, which is filled in from the token's tid claim.
+	expected := p.issuer
+	if strings.Contains(expected, "{tenantid}") {
+		tenant, _ := claims["tid"].(string)
+		if tenant == "" {
+			return nil, errors.New("ID token has no tenant")
+		}
+		expected = strings.ReplaceAll(expected, "{tenantid}", tenant)
+	}
+	issuer, err := claims.GetIssuer()
+	if err != nil || expected == "" || normalizeIssuer(issuer) != normalizeIssuer(expected) {
+		return nil, errors.New("issuer mismatch")
+	}
+
+	return claims, nil
+}
+
+// normalizeIssuer lets "accounts.google.com" match "https://accounts.google.com",
+// since Google issues ID tokens with either form.
+func normalizeIssuer(issuer string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(issuer, "https://"), "/")
+}
+
+// GitHubProvider signs users in with GitHub, which only speaks plain OAuth2,
+// so the profile is read from the REST API instead of an ID token.
+type GitHubProvider struct {
+	apiURL string
+	config oauth2.Config
+}
+
+func (p *GitHubProvider) Name() string {
+	return "github"
+}
+
+func (p *GitHubProvider) AuthCodeURL(state *OAuthState) string {
+	return p.config.AuthCodeURL(state.State, oauth2.S256ChallengeOption(state.Verifier))
+}
+
+func (p *GitHubProvider) Exchange(ctx context.Context, code string, state *OAuthState) (*OAuthUserInfo, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, oauthHTTPClient)
+
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange token: %w", err)
+	}
+
+	var profile struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Email string `json:"email"`
+	}
+	if err := getJSON(ctx, p.config.Client(ctx, token), p.apiURL+"/user", &profile); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+
+	if profile.ID == 0 {
+		return nil, errors.New("GitHub profile has no ID")
+	}
+
+	return &OAuthUserInfo{
+		Subject: strconv.FormatInt(profile.ID, 10),
+		Name:    profile.Login,
+		Email:   profile.Email,
+	}, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
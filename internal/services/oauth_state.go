@@ -0,0 +1,140 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oauthStateCookie   = "oauth_state"
+	oauthStateDuration = 10 * time.Minute
+)
+
+var (
+	ErrInvalidOAuthState  = errors.New("invalid oauth state")
+	ErrReturnToNotAllowed = errors.New("return_to is not allowed")
+)
+
+// OAuthState is kept in a short-lived signed cookie between the redirect to
+// the provider and the callback, so the callback can check that it answers a
+// flow this browser started.
+type OAuthState struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+	// LinkUserID is set when a signed-in user links the provider identity to
+	// their account instead of signing in with it.
+	LinkUserID uint `json:"link_user_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func NewOAuthState(provider, returnTo string) (*OAuthState, error) {
+	state, err := RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OAuthState{
+		Provider: provider,
+		State:    state,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+		ReturnTo: returnTo,
+	}, nil
+}
+
+func SetOAuthStateCookie(c *gin.Context, state *OAuthState) error {
+	state.ExpiresAt = jwt.NewNumericDate(time.Now().Add(oauthStateDuration))
+	signed, err := SignClaims(state)
+	if err != nil {
+		return err
+	}
+
+	SetCookie(c, oauthStateCookie, signed, int(oauthStateDuration.Seconds()))
+	return nil
+}
+
+// ConsumeOAuthStateCookie reads and clears the state cookie and checks it
+// against the provider handling the callback and the state it returned.
+func ConsumeOAuthStateCookie(c *gin.Context, provider, returnedState string) (*OAuthState, error) {
+	cookie, err := c.Cookie(oauthStateCookie)
+	if err != nil {
+		return nil, ErrInvalidOAuthState
+	}
+	SetCookie(c, oauthStateCookie, "", -1)
+
+	state := &OAuthState{}
+	if err := ParseClaims(cookie, state); err != nil {
+		return nil, ErrInvalidOAuthState
+	}
+
+	if state.Provider != provider {
+		return nil, ErrInvalidOAuthState
+	}
+
+	if returnedState == "" || subtle.ConstantTimeCompare([]byte(state.State), []byte(returnedState)) != 1 {
+		return nil, ErrInvalidOAuthState
+	}
+
+	return state, nil
+}
+
+// ResolveReturnTo turns the requested post-login target into an absolute URL.
+// Relative paths are resolved against FRONTEND_REDIRECT_URL, and absolute URLs
+// must share the origin of FRONTEND_REDIRECT_URL or one of the comma separated
+// ALLOWED_REDIRECT_URLS.
+func ResolveReturnTo(returnTo string) (string, error) {
+	defaultURL := os.Getenv("FRONTEND_REDIRECT_URL")
+	if returnTo == "" {
+		return defaultURL, nil
+	}
+
+	target, err := url.Parse(returnTo)
+	if err != nil {
+		return "", ErrReturnToNotAllowed
+	}
+
+	if !target.IsAbs() {
+		if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+			return "", ErrReturnToNotAllowed
+		}
+
+		base, err := url.Parse(defaultURL)
+		if err != nil {
+			return "", ErrReturnToNotAllowed
+		}
+		return base.ResolveReference(target).String(), nil
+	}
+
+	allowedURLs := []string{defaultURL}
+	if allowed := os.Getenv("ALLOWED_REDIRECT_URLS"); allowed != "" {
+		allowedURLs = append(allowedURLs, strings.Split(allowed, ",")...)
+	}
+
+	for _, allowedURL := range allowedURLs {
+		allowed, err := url.Parse(strings.TrimSpace(allowedURL))
+		if err != nil || allowed.Host == "" {
+			continue
+		}
+		if strings.EqualFold(allowed.Scheme, target.Scheme) && strings.EqualFold(allowed.Host, target.Host) {
+			return target.String(), nil
+		}
+	}
+
+	return "", ErrReturnToNotAllowed
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/lib/pq"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	passkeyCeremonyCookie   = "passkey_ceremony"
+	passkeyCeremonyDuration = 5 * time.Minute
+
+	passkeyRegistration = "registration"
+	passkeyLogin        = "login"
+)
+
+var (
+	ErrPasskeysNotConfigured = errors.New("passkeys are not configured")
+	ErrInvalidPasskey        = errors.New("invalid passkey ceremony")
+)
+
+// passkeyCeremony carries the WebAuthn session data between the begin and
+// finish requests of a ceremony in a short-lived signed cookie.
+type passkeyCeremony struct {
+	Purpose string               `json:"purpose"`
+	UserID  uint                 `json:"user_id,omitempty"`
+	Name    string               `json:"name,omitempty"`
+	Session webauthn.SessionData `json:"session"`
+	jwt.RegisteredClaims
+}
+
+// PasskeyManager runs the WebAuthn registration and login ceremonies.
+// Passkeys are registered as discoverable credentials that require user
+// verification, so they can sign in without a username or second factor.
+// Each ceremony's challenge is accepted once, so a captured response cannot
+// be replayed with the ceremony cookie it was made for.
+type PasskeyManager struct {
+	db       *gorm.DB
+	webAuthn *webauthn.WebAuthn
+	attempts AttemptStore
+}
+
+// NewPasskeyManager reads the relying party from WEBAUTHN_RP_ID and the comma
+// separated WEBAUTHN_RP_ORIGINS, which default to the host and origin of
+// FRONTEND_REDIRECT_URL. WEBAUTHN_RP_NAME defaults to Olympliance.
+func NewPasskeyManager(db *gorm.DB, attempts AttemptStore) *PasskeyManager {
+	manager := &PasskeyManager{db: db, attempts: attempts}
+
+	rpID := os.Getenv("WEBAUTHN_RP_ID")
+	origins := splitList(os.Getenv("WEBAUTHN_RP_ORIGINS"))
+	if frontend, err := url.Parse(os.Getenv("FRONTEND_REDIRECT_URL")); err == nil && frontend.Host != "" {
+		if rpID == "" {
+			rpID = frontend.Hostname()
+		}
+		if len(origins) == 0 {
+			origins = []string{frontend.Scheme + "://" + frontend.Host}
+		}
+	}
+
+	rpName := os.Getenv("WEBAUTHN_RP_NAME")
+	if rpName == "" {
+		rpName = "Olympliance"
+	}
+
+	webAuthn, err := webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: rpName,
+		RPOrigins:     origins,
+	})
+	if err != nil {
+		log.Printf("Warning: passkeys disabled: %v", err)
+		return manager
+	}
+
+	manager.webAuthn = webAuthn
+	return manager
+}
+
+// BeginRegistration returns the options for navigator.credentials.create.
+func (m *PasskeyManager) BeginRegistration(c *gin.Context, user *models.User, name string) (*protocol.CredentialCreation, error) {
+	if m.webAuthn == nil {
+		return nil, ErrPasskeysNotConfigured
+	}
+
+	passkeyUser, err := m.loadUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(passkeyUser.credentials))
+	for _, credential := range passkeyUser.credentials {
+		exclusions = append(exclusions, credential.Descriptor())
+	}
+
+	creation, session, err := m.webAuthn.BeginRegistration(passkeyUser,
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementRequired,
+			UserVerification: protocol.VerificationRequired,
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.setCeremony(c, passkeyCeremony{
+		Purpose: passkeyRegistration,
+		UserID:  user.UserID,
+		Name:    name,
+		Session: *session,
+	}); err != nil {
+		return nil, err
+	}
+
+	return creation, nil
+}
+
+// FinishRegistration checks the attestation in the request body and stores
+// the new credential.
+func (m *PasskeyManager) FinishRegistration(c *gin.Context, user *models.User) (*models.WebAuthnCredential, error) {
+	if m.webAuthn == nil {
+		return nil, ErrPasskeysNotConfigured
+	}
+
+	ceremony, err := m.consumeCeremony(c, passkeyRegistration)
+	if err != nil || ceremony.UserID != user.UserID {
+		return nil, ErrInvalidPasskey
+	}
+
+	passkeyUser, err := m.loadUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := m.webAuthn.FinishRegistration(passkeyUser, ceremony.Session, c.Request)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	transports := make(pq.StringArray, len(credential.Transport))
+	for i, transport := range credential.Transport {
+		transports[i] = string(transport)
+	}
+
+	stored := &models.WebAuthnCredential{
+		UserID:          user.UserID,
+		Name:            ceremony.Name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		UserVerified:    credential.Flags.UserVerified,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+	if err := m.db.Create(stored).Error; err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// BeginLogin returns the options for navigator.credentials.get. Without a
+// username the browser lets the user pick any passkey registered for this
+// site. With one, only that user's passkeys are allowed, which also works
+// for authenticators that did not store the credential as discoverable.
+func (m *PasskeyManager) BeginLogin(c *gin.Context, username string) (*protocol.CredentialAssertion, error) {
+	if m.webAuthn == nil {
+		return nil, ErrPasskeysNotConfigured
+	}
+
+	if username == "" {
+		assertion, session, err := m.webAuthn.BeginDiscoverableLogin(
+			webauthn.WithUserVerification(protocol.VerificationRequired),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := m.setCeremony(c, passkeyCeremony{Purpose: passkeyLogin, Session: *session}); err != nil {
+			return nil, err
+		}
+		return assertion, nil
+	}
+
+	var user models.User
+	if err := m.db.Where("username = ? AND is_deleted = ?", username, false).First(&user).Error; err != nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	passkeyUser, err := m.loadUser(&user)
+	if err != nil {
+		return nil, err
+	}
+	if len(passkeyUser.credentials) == 0 {
+		return nil, ErrInvalidPasskey
+	}
+
+	assertion, session, err := m.webAuthn.BeginLogin(passkeyUser,
+		webauthn.WithUserVerification(protocol.VerificationRequired),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := m.setCeremony(c, passkeyCeremony{Purpose: passkeyLogin, UserID: user.UserID, Session: *session}); err != nil {
+		return nil, err
+	}
+	return assertion, nil
+}
+
+// FinishLogin checks the assertion in the request body and returns the user
+// who owns the passkey.
+func (m *PasskeyManager) FinishLogin(c *gin.Context) (*models.User, error) {
+	if m.webAuthn == nil {
+		return nil, ErrPasskeysNotConfigured
+	}
+
+	ceremony, err := m.consumeCeremony(c, passkeyLogin)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	var (
+		owner      *passkeyUser
+		credential *webauthn.Credential
+	)
+	if ceremony.UserID != 0 {
+		var user models.User
+		if err := m.db.First(&user, ceremony.UserID).Error; err != nil {
+			return nil, ErrInvalidPasskey
+		}
+
+		if owner, err = m.loadUser(&user); err != nil {
+			return nil, err
+		}
+
+		credential, err = m.webAuthn.FinishLogin(owner, ceremony.Session, c.Request)
+	} else {
+		credential, err = m.webAuthn.FinishDiscoverableLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			if len(userHandle) != 8 {
+				return nil, ErrInvalidPasskey
+			}
+
+			var user models.User
+			if err := m.db.First(&user, binary.BigEndian.Uint64(userHandle)).Error; err != nil {
+				return nil, ErrInvalidPasskey
+			}
+
+			loaded, err := m.loadUser(&user)
+			if err != nil {
+				return nil, err
+			}
+
+			owner = loaded
+			return loaded, nil
+		}, ceremony.Session, c.Request)
+	}
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+
+	// A signature counter that went backwards means the key may have been
+	// cloned, so the login is refused.
+	if credential.Authenticator.CloneWarning {
+		log.Printf("Warning: possible cloned passkey for user %d", owner.user.UserID)
+		return nil, ErrInvalidPasskey
+	}
+
+	if err := m.db.Model(&models.WebAuthnCredential{}).
+		Where("credential_id = ?", credential.ID).
+		Updates(map[string]interface{}{
+			"sign_count":   credential.Authenticator.SignCount,
+			"backup_state": credential.Flags.BackupState,
+			"last_used_at": time.Now(),
+		}).Error; err != nil {
+		return nil, err
+	}
+
+	return owner.user, nil
+}
+
+func (m *PasskeyManager) loadUser(user *models.User) (*passkeyUser, error) {
+	var stored []models.WebAuthnCredential
+	if err := m.db.Where("user_id = ?", user.UserID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	credentials := make([]webauthn.Credential, len(stored))
+	for i, credential := range stored {
+		transports := make([]protocol.AuthenticatorTransport, len(credential.Transports))
+		for j, transport := range credential.Transports {
+			transports[j] = protocol.AuthenticatorTransport(transport)
+		}
+
+		credentials[i] = webauthn.Credential{
+			ID:              credential.CredentialID,
+			PublicKey:       credential.PublicKey,
+			AttestationType: credential.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				UserPresent:    true,
+				UserVerified:   credential.UserVerified,
+				BackupEligible: credential.BackupEligible,
+				BackupState:    credential.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    credential.AAGUID,
+				SignCount: credential.SignCount,
+			},
+		}
+	}
+
+	return &passkeyUser{user: user, credentials: credentials}, nil
+}
+
+func (m *PasskeyManager) setCeremony(c *gin.Context, ceremony passkeyCeremony) error {
+	ceremony.ExpiresAt = jwt.NewNumericDate(time.Now().Add(passkeyCeremonyDuration))
+	signed, err := SignClaims(ceremony)
+	if err != nil {
+		return err
+	}
+
+	SetCookie(c, passkeyCeremonyCookie, signed, int(passkeyCeremonyDuration.Seconds()))
+	return nil
+}
+
+func (m *PasskeyManager) consumeCeremony(c *gin.Context, purpose string) (*passkeyCeremony, error) {
+	cookie, err := c.Cookie(passkeyCeremonyCookie)
+	if err != nil {
+		return nil, ErrInvalidPasskey
+	}
+	SetCookie(c, passkeyCeremonyCookie, "", -1)
+
+	ceremony := &passkeyCeremony{}
+	if err := ParseClaims(cookie, ceremony); err != nil || ceremony.Purpose != purpose {
+		return nil, ErrInvalidPasskey
+	}
+
+	// Clearing the cookie does not stop a copy of it from being sent again,
+	// so the challenge is also marked as used until the cookie expires.
+	_, first, err := m.attempts.AddHit("passkey:"+ceremony.Session.Challenge, time.Now(), passkeyCeremonyDuration, 1)
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		return nil, ErrInvalidPasskey
+	}
+
+	return ceremony, nil
+}
+
+// passkeyUser adapts a user and their stored credentials to webauthn.User.
+// The user handle is the big-endian user ID.
+type passkeyUser struct {
+	user        *models.User
+	credentials []webauthn.Credential
+}
+
+func (u *passkeyUser) WebAuthnID() []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(u.user.UserID))
+	return handle
+}
+
+func (u *passkeyUser) WebAuthnName() string {
+	return u.user.Username
+}
+
+func (u *passkeyUser) WebAuthnDisplayName() string {
+	return u.user.Username
+}
+
+func (u *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+// PasskeyName trims a user-chosen passkey name and falls back to a default.
+func PasskeyName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "Passkey"
+	}
+	if len([]rune(name)) > 64 {
+		name = string([]rune(name)[:64])
+	}
+	return name
+}
@@ -0,0 +1,447 @@
+package services
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	testRPID   = "olympliance.test"
+	testOrigin = "https://olympliance.test"
+)
+
+// softPasskey is a software authenticator holding one ES256 credential. Like
+// most synced passkeys it never increments its signature counter, so replays
+// are only caught by the single-use challenge.
+type softPasskey struct {
+	id         []byte
+	key        *ecdsa.PrivateKey
+	userHandle []byte
+}
+
+func newSoftPasskey(t *testing.T, user *models.User) *softPasskey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatal(err)
+	}
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(user.UserID))
+
+	return &softPasskey{id: id, key: key, userHandle: handle}
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func clientDataJSON(t *testing.T, ceremonyType string, challenge []byte, origin string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"type":        ceremonyType,
+		"challenge":   b64(challenge),
+		"origin":      origin,
+		"crossOrigin": false,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// authenticatorData sets the user present and user verified flags, plus the
+// attested credential data when registering.
+func (p *softPasskey) authenticatorData(t *testing.T, attested bool) []byte {
+	t.Helper()
+
+	rpIDHash := sha256.Sum256([]byte(testRPID))
+	data := append([]byte(nil), rpIDHash[:]...)
+	flags := byte(0x01 | 0x04)
+	if attested {
+		flags |= 0x40
+	}
+	data = append(data, flags, 0, 0, 0, 0)
+	if !attested {
+		return data
+	}
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: p.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		YCoord: p.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data = append(data, make([]byte, 16)...)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(p.id)))
+	data = append(data, p.id...)
+	return append(data, publicKey...)
+}
+
+// create answers navigator.credentials.create with "none" attestation.
+func (p *softPasskey) create(t *testing.T, challenge []byte, origin string) []byte {
+	t.Helper()
+
+	attestation, err := webauthncbor.Marshal(struct {
+		Format       string                 `cbor:"fmt"`
+		AttStatement map[string]interface{} `cbor:"attStmt"`
+		AuthData     []byte                 `cbor:"authData"`
+	}{
+		Format:       "none",
+		AttStatement: map[string]interface{}{},
+		AuthData:     p.authenticatorData(t, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":    b64(p.id),
+		"rawId": b64(p.id),
+		"type":  "public-key",
+		"response": map[string]interface{}{
+			"clientDataJSON":    b64(clientDataJSON(t, "webauthn.create", challenge, origin)),
+			"attestationObject": b64(attestation),
+			"transports":        []string{"internal"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// get answers navigator.credentials.get. Credentials that are not
+// discoverable return no user handle.
+func (p *softPasskey) get(t *testing.T, challenge []byte, origin string, discoverable bool) []byte {
+	t.Helper()
+
+	authData := p.authenticatorData(t, false)
+	clientData := clientDataJSON(t, "webauthn.get", challenge, origin)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, p.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := map[string]interface{}{
+		"clientDataJSON":    b64(clientData),
+		"authenticatorData": b64(authData),
+		"signature":         b64(signature),
+	}
+	if discoverable {
+		response["userHandle"] = b64(p.userHandle)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":       b64(p.id),
+		"rawId":    b64(p.id),
+		"type":     "public-key",
+		"response": response,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func newPasskeyTestManager(t *testing.T) (*PasskeyManager, *gorm.DB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("BACKEND_DOMAIN", "localhost")
+	t.Setenv("WEBAUTHN_RP_ID", testRPID)
+	t.Setenv("WEBAUTHN_RP_ORIGINS", testOrigin)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to an in-memory database gets its own database.
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&models.User{}, &models.WebAuthnCredential{}); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewPasskeyManager(db, NewInMemoryAttemptStore())
+	if manager.webAuthn == nil {
+		t.Fatal("passkeys are not configured")
+	}
+	return manager, db
+}
+
+func createPasskeyTestUser(t *testing.T, db *gorm.DB, username string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// ceremonyStep runs one request of a ceremony, sending the cookies set by
+// the previous step.
+func ceremonyStep(cookies []*http.Cookie, body []byte, handle func(c *gin.Context)) []*http.Cookie {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	for _, cookie := range cookies {
+		c.Request.AddCookie(cookie)
+	}
+	handle(c)
+	return recorder.Result().Cookies()
+}
+
+func registerPasskey(t *testing.T, m *PasskeyManager, user *models.User, name string) *softPasskey {
+	t.Helper()
+
+	passkey := newSoftPasskey(t, user)
+	var challenge []byte
+	cookies := ceremonyStep(nil, nil, func(c *gin.Context) {
+		creation, err := m.BeginRegistration(c, user, name)
+		if err != nil {
+			t.Fatalf("BeginRegistration: %v", err)
+		}
+		challenge = creation.Response.Challenge
+	})
+
+	ceremonyStep(cookies, passkey.create(t, challenge, testOrigin), func(c *gin.Context) {
+		stored, err := m.FinishRegistration(c, user)
+		if err != nil {
+			t.Fatalf("FinishRegistration: %v", err)
+		}
+		if stored.Name != name || !bytes.Equal(stored.CredentialID, passkey.id) || !stored.UserVerified {
+			t.Fatalf("unexpected stored passkey %+v", stored)
+		}
+	})
+
+	return passkey
+}
+
+// loginWithPasskey runs a login ceremony and returns the finish request's
+// cookies and body so tests can replay them.
+func loginWithPasskey(t *testing.T, m *PasskeyManager, passkey *softPasskey, username, origin string) (*models.User, []*http.Cookie, []byte, error) {
+	t.Helper()
+
+	var challenge []byte
+	cookies := ceremonyStep(nil, nil, func(c *gin.Context) {
+		assertion, err := m.BeginLogin(c, username)
+		if err != nil {
+			t.Fatalf("BeginLogin: %v", err)
+		}
+		challenge = assertion.Response.Challenge
+	})
+
+	body := passkey.get(t, challenge, origin, username == "")
+	user, err := finishPasskeyLogin(m, cookies, body)
+	return user, cookies, body, err
+}
+
+func finishPasskeyLogin(m *PasskeyManager, cookies []*http.Cookie, body []byte) (*models.User, error) {
+	var (
+		user *models.User
+		err  error
+	)
+	ceremonyStep(cookies, body, func(c *gin.Context) {
+		user, err = m.FinishLogin(c)
+	})
+	return user, err
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	m, db := newPasskeyTestManager(t)
+	user := createPasskeyTestUser(t, db, "ada")
+	passkey := registerPasskey(t, m, user, "Laptop")
+
+	t.Run("discoverable", func(t *testing.T) {
+		owner, _, _, err := loginWithPasskey(t, m, passkey, "", testOrigin)
+		if err != nil {
+			t.Fatalf("FinishLogin: %v", err)
+		}
+		if owner.UserID != user.UserID {
+			t.Fatalf("logged in as %d, want %d", owner.UserID, user.UserID)
+		}
+	})
+
+	t.Run("non-discoverable", func(t *testing.T) {
+		ceremonyStep(nil, nil, func(c *gin.Context) {
+			assertion, err := m.BeginLogin(c, "ada")
+			if err != nil {
+				t.Fatal(err)
+			}
+			allowed := assertion.Response.AllowedCredentials
+			if len(allowed) != 1 || !bytes.Equal(allowed[0].CredentialID, passkey.id) {
+				t.Fatalf("allowed credentials = %+v", allowed)
+			}
+		})
+
+		owner, _, _, err := loginWithPasskey(t, m, passkey, "ada", testOrigin)
+		if err != nil {
+			t.Fatalf("FinishLogin: %v", err)
+		}
+		if owner.UserID != user.UserID {
+			t.Fatalf("logged in as %d, want %d", owner.UserID, user.UserID)
+		}
+	})
+
+	var stored models.WebAuthnCredential
+	if err := db.Where("credential_id = ?", passkey.id).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("last_used_at was not recorded")
+	}
+}
+
+func TestPasskeyLoginRejectsUnknownUsername(t *testing.T) {
+	m, db := newPasskeyTestManager(t)
+	createPasskeyTestUser(t, db, "ada")
+
+	for _, username := range []string{"ada", "nobody"} {
+		ceremonyStep(nil, nil, func(c *gin.Context) {
+			if _, err := m.BeginLogin(c, username); !errors.Is(err, ErrInvalidPasskey) {
+				t.Fatalf("BeginLogin(%q) error = %v, want ErrInvalidPasskey", username, err)
+			}
+		})
+	}
+}
+
+func TestPasskeyLoginWithSeveralPasskeys(t *testing.T) {
+	m, db := newPasskeyTestManager(t)
+	ada := createPasskeyTestUser(t, db, "ada")
+	grace := createPasskeyTestUser(t, db, "grace")
+
+	laptop := registerPasskey(t, m, ada, "Laptop")
+	phone := registerPasskey(t, m, ada, "Phone")
+	other := registerPasskey(t, m, grace, "Key")
+
+	var count int64
+	db.Model(&models.WebAuthnCredential{}).Where("user_id = ?", ada.UserID).Count(&count)
+	if count != 2 {
+		t.Fatalf("ada has %d passkeys, want 2", count)
+	}
+
+	// A new registration excludes the passkeys the user already has.
+	ceremonyStep(nil, nil, func(c *gin.Context) {
+		creation, err := m.BeginRegistration(c, ada, "Tablet")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(creation.Response.CredentialExcludeList) != 2 {
+			t.Errorf("exclude list has %d passkeys, want 2", len(creation.Response.CredentialExcludeList))
+		}
+	})
+
+	tests := []struct {
+		name     string
+		passkey  *softPasskey
+		username string
+		want     uint
+	}{
+		{name: "first passkey", passkey: laptop, want: ada.UserID},
+		{name: "second passkey", passkey: phone, want: ada.UserID},
+		{name: "second passkey by username", passkey: phone, username: "ada", want: ada.UserID},
+		{name: "other user", passkey: other, want: grace.UserID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner, _, _, err := loginWithPasskey(t, m, tt.passkey, tt.username, testOrigin)
+			if err != nil {
+				t.Fatalf("FinishLogin: %v", err)
+			}
+			if owner.UserID != tt.want {
+				t.Fatalf("logged in as %d, want %d", owner.UserID, tt.want)
+			}
+		})
+	}
+
+	t.Run("passkey of another user by username", func(t *testing.T) {
+		if _, _, _, err := loginWithPasskey(t, m, other, "ada", testOrigin); !errors.Is(err, ErrInvalidPasskey) {
+			t.Fatalf("FinishLogin error = %v, want ErrInvalidPasskey", err)
+		}
+	})
+}
+
+func TestPasskeyLoginRejectsReplayedAssertion(t *testing.T) {
+	m, db := newPasskeyTestManager(t)
+	user := createPasskeyTestUser(t, db, "ada")
+	passkey := registerPasskey(t, m, user, "Laptop")
+
+	for _, username := range []string{"", "ada"} {
+		_, cookies, body, err := loginWithPasskey(t, m, passkey, username, testOrigin)
+		if err != nil {
+			t.Fatalf("FinishLogin: %v", err)
+		}
+
+		if _, err := finishPasskeyLogin(m, cookies, body); !errors.Is(err, ErrInvalidPasskey) {
+			t.Fatalf("replayed FinishLogin error = %v, want ErrInvalidPasskey", err)
+		}
+	}
+}
+
+func TestPasskeyCeremoniesRejectWrongOrigin(t *testing.T) {
+	m, db := newPasskeyTestManager(t)
+	user := createPasskeyTestUser(t, db, "ada")
+	passkey := registerPasskey(t, m, user, "Laptop")
+
+	t.Run("login", func(t *testing.T) {
+		if _, _, _, err := loginWithPasskey(t, m, passkey, "", "https://evil.test"); !errors.Is(err, ErrInvalidPasskey) {
+			t.Fatalf("FinishLogin error = %v, want ErrInvalidPasskey", err)
+		}
+	})
+
+	t.Run("registration", func(t *testing.T) {
+		phishing := newSoftPasskey(t, user)
+		var challenge []byte
+		cookies := ceremonyStep(nil, nil, func(c *gin.Context) {
+			creation, err := m.BeginRegistration(c, user, "Phone")
+			if err != nil {
+				t.Fatal(err)
+			}
+			challenge = creation.Response.Challenge
+		})
+
+		ceremonyStep(cookies, phishing.create(t, challenge, "https://evil.test"), func(c *gin.Context) {
+			if _, err := m.FinishRegistration(c, user); !errors.Is(err, ErrInvalidPasskey) {
+				t.Fatalf("FinishRegistration error = %v, want ErrInvalidPasskey", err)
+			}
+		})
+	})
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+)
+
+const (
+	PasswordTooShort        = "too_short"
+	PasswordTooLong         = "too_long"
+	PasswordSimilarToUser   = "similar_to_username"
+	PasswordFoundInBreaches = "breached"
+)
+
+// PasswordViolation is one rule a password breaks. Code is stable so the
+// frontend can show its own message.
+type PasswordViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// PasswordPolicy checks new passwords. The breached list holds the SHA-1
+// digests of known leaked passwords so the plain passwords are never kept in
+// memory.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	breached  map[string]struct{}
+}
+
+var (
+	defaultPasswordPolicy     *PasswordPolicy
+	defaultPasswordPolicyErr  error
+	defaultPasswordPolicyOnce sync.Once
+)
+
+// DefaultPasswordPolicy loads the policy from the environment on first use.
+func DefaultPasswordPolicy() (*PasswordPolicy, error) {
+	defaultPasswordPolicyOnce.Do(func() {
+		defaultPasswordPolicy, defaultPasswordPolicyErr = LoadPasswordPolicyFromEnv()
+	})
+	return defaultPasswordPolicy, defaultPasswordPolicyErr
+}
+
+// LoadPasswordPolicyFromEnv reads PASSWORD_MIN_LENGTH (default 8),
+// PASSWORD_MAX_LENGTH (default 128) and PASSWORD_BREACHED_LIST_FILE, a file
+// with one password per line. Lines may instead hold SHA-1 digests in the
+// format of the Have I Been Pwned downloads, optionally followed by ":count".
+func LoadPasswordPolicyFromEnv() (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{MinLength: 8, MaxLength: 128, breached: map[string]struct{}{}}
+
+	if minLength, err := strconv.Atoi(os.Getenv("PASSWORD_MIN_LENGTH")); err == nil && minLength > 0 {
+		policy.MinLength = minLength
+	}
+	if maxLength, err := strconv.Atoi(os.Getenv("PASSWORD_MAX_LENGTH")); err == nil && maxLength > 0 {
+		policy.MaxLength = maxLength
+	}
+	if policy.MaxLength < policy.MinLength {
+		return nil, fmt.Errorf("PASSWORD_MAX_LENGTH %d is below PASSWORD_MIN_LENGTH %d", policy.MaxLength, policy.MinLength)
+	}
+
+	if path := os.Getenv("PASSWORD_BREACHED_LIST_FILE"); path != "" {
+		if err := policy.loadBreachedList(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return policy, nil
+}
+
+func (p *PasswordPolicy) loadBreachedList(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if digest, _, _ := strings.Cut(line, ":"); isSHA1Hex(digest) {
+			p.breached[strings.ToUpper(digest)] = struct{}{}
+			continue
+		}
+
+		p.breached[sha1Hex(line)] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return nil
+}
+
+// Check returns a *PasswordPolicyError listing every rule the password
+// breaks. Identifiers are the username, email and other values the password
+// must not resemble.
+func (p *PasswordPolicy) Check(password string, identifiers ...string) error {
+	var violations []PasswordViolation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooShort,
+			Message: fmt.Sprintf("Password must be at least %d characters long", p.MinLength),
+		})
+	}
+	if length > p.MaxLength {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooLong,
+			Message: fmt.Sprintf("Password must be at most %d characters long", p.MaxLength),
+		})
+	}
+
+	if resemblesAny(password, identifiers) {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordSimilarToUser,
+			Message: "Password must not contain or resemble your username or email",
+		})
+	}
+
+	if _, found := p.breached[sha1Hex(password)]; found {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordFoundInBreaches,
+			Message: "Password appears in a list of leaked passwords, choose a different one",
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// CheckPassword checks a password against the default policy.
+func CheckPassword(password string, identifiers ...string) error {
+	policy, err := DefaultPasswordPolicy()
+	if err != nil {
+		return err
+	}
+	return policy.Check(password, identifiers...)
+}
+
+// UserIdentifiers lists the values of an account its password must not
+// resemble.
+func UserIdentifiers(user *models.User) []string {
+	identifiers := []string{user.Username}
+	if user.Email != nil {
+		identifiers = append(identifiers, *user.Email)
+	}
+	return identifiers
+}
+
+// RespondPasswordError writes the response for an error from CheckPassword.
+func RespondPasswordError(c *gin.Context, err error) {
+	if policyErr, ok := err.(*PasswordPolicyError); ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "Password does not meet the requirements",
+			"violations": policyErr.Violations,
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check password"})
+}
+
+// resemblesAny compares letters and digits only, case-insensitively, and
+// also catches identifiers written backwards. Values shorter than three
+// characters are not compared.
+func resemblesAny(password string, identifiers []string) bool {
+	normalizedPassword := normalizeForComparison(password)
+	if normalizedPassword == "" {
+		return false
+	}
+
+	for _, identifier := range identifiers {
+		identifier, _, _ = strings.Cut(identifier, "@")
+		normalized := normalizeForComparison(identifier)
+		if len(normalized) < 3 {
+			continue
+		}
+
+		if strings.Contains(normalizedPassword, normalized) ||
+			strings.Contains(normalizedPassword, reverse(normalized)) ||
+			(len(normalizedPassword) >= 3 && strings.Contains(normalized, normalizedPassword)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeForComparison(value string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(value) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+func reverse(value string) string {
+	runes := []rune(value)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+func sha1Hex(value string) string {
+	sum := sha1.Sum([]byte(value))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func isSHA1Hex(value string) bool {
+	if len(value) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	SettingPremoderationMinReputation = "premoderation_min_reputation"
+	SettingPremoderationMinAccountAge = "premoderation_min_account_age_hours"
+)
+
+// PremoderationThresholds hold back the posts of users below either of them.
+// Zero turns a threshold off.
+type PremoderationThresholds struct {
+	MinReputation      int `json:"min_reputation"`
+	MinAccountAgeHours int `json:"min_account_age_hours"`
+}
+
+// PremoderationManager decides which new threads and comments wait for a
+// moderator to approve them.
+type PremoderationManager struct {
+	db *gorm.DB
+}
+
+func NewPremoderationManager(db *gorm.DB) *PremoderationManager {
+	return &PremoderationManager{db: db}
+}
+
+func (m *PremoderationManager) Thresholds() (*PremoderationThresholds, error) {
+	reputation, err := GetSetting(m.db, SettingPremoderationMinReputation, "0")
+	if err != nil {
+		return nil, err
+	}
+	accountAge, err := GetSetting(m.db, SettingPremoderationMinAccountAge, "0")
+	if err != nil {
+		return nil, err
+	}
+
+	var thresholds PremoderationThresholds
+	if thresholds.MinReputation, err = strconv.Atoi(reputation); err != nil {
+		return nil, err
+	}
+	if thresholds.MinAccountAgeHours, err = strconv.Atoi(accountAge); err != nil {
+		return nil, err
+	}
+	return &thresholds, nil
+}
+
+func (m *PremoderationManager) SetThresholds(thresholds *PremoderationThresholds) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if err := SetSetting(tx, SettingPremoderationMinReputation, strconv.Itoa(thresholds.MinReputation)); err != nil {
+			return err
+		}
+		return SetSetting(tx, SettingPremoderationMinAccountAge, strconv.Itoa(thresholds.MinAccountAgeHours))
+	})
+}
+
+// IsRequired reports whether the posts of the user need approval. Staff never
+// need it.
+func (m *PremoderationManager) IsRequired(user *models.User) (bool, error) {
+	isStaff, err := NewRBACManager(m.db).IsStaff(user)
+	if err != nil || isStaff {
+		return false, err
+	}
+
+	thresholds, err := m.Thresholds()
+	if err != nil {
+		return false, err
+	}
+
+	if user.Reputation < thresholds.MinReputation {
+		return true, nil
+	}
+	return time.Since(user.CreatedAt) < time.Duration(thresholds.MinAccountAgeHours)*time.Hour, nil
+}
+
+// PendingThreads lists threads awaiting approval, oldest first. A nil
+// categoryIDs includes every category.
+func (m *PremoderationManager) PendingThreads(categoryIDs []uint, limit, offset int) ([]models.Thread, int64, error) {
+	query := m.db.Model(&models.Thread{}).Where("visibility = ? AND is_deleted = ?", VisibilityPending, false)
+	if categoryIDs != nil {
+		query = query.Where("category_id IN ?", categoryIDs)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	threads := []models.Thread{}
+	err := query.Order("created_at, thread_id").Limit(limit).Offset(offset).Find(&threads).Error
+	return threads, total, err
+}
+
+// PendingComments lists comments awaiting approval, oldest first. A nil
+// categoryIDs includes every category.
+func (m *PremoderationManager) PendingComments(categoryIDs []uint, limit, offset int) ([]models.Comment, int64, error) {
+	query := m.db.Model(&models.Comment{}).
+		Joins("JOIN threads ON threads.thread_id = comments.thread_id").
+		Where("comments.visibility = ? AND comments.is_deleted = ?", VisibilityPending, false)
+	if categoryIDs != nil {
+		query = query.Where("threads.category_id IN ?", categoryIDs)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	comments := []models.Comment{}
+	err := query.Select("comments.*").
+		Order("comments.created_at, comments.comment_id").
+		Limit(limit).
+		Offset(offset).
+		Find(&comments).Error
+	return comments, total, err
+}
+
+// AdjustCommentCount changes the number of comments in a thread's stats,
+// which only counts visible comments. Held and pending comments are counted
+// when they are released or approved.
+func AdjustCommentCount(db *gorm.DB, threadID uint, adjustment int) error {
+	return db.Model(&models.Thread{}).
+		Where("thread_id = ?", threadID).
+		Update("stats", gorm.Expr("jsonb_set(stats, '{comments}', to_jsonb(((stats->>'comments')::int + ?)::int))", adjustment)).
+		Error
+}
+
+// VisibleTo limits a query of threads or comments to visible ones and those
+// written by the viewer, who is nil for guests.
+func VisibleTo(viewer *models.User) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer == nil {
+			return db.Where("visibility = ?", VisibilityVisible)
+		}
+		return db.Where("visibility = ? OR user_id = ?", VisibilityVisible, viewer.UserID)
+	}
+}
@@ -0,0 +1,343 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	PermissionThreadDeleteAny   = "thread.delete.any"
+	PermissionCommentDeleteAny  = "comment.delete.any"
+	PermissionUserBan           = "user.ban"
+	PermissionUserLookup        = "user.lookup"
+	PermissionModeratorAssign   = "moderator.assign"
+	PermissionRoleAssign        = "role.assign"
+	PermissionCategoryManage    = "category.manage"
+	PermissionSettingsManage    = "settings.manage"
+	PermissionUserImpersonate   = "user.impersonate"
+	PermissionModerationLogView = "moderation_log.view"
+	PermissionReportReview      = "report.review"
+	PermissionAutomodManage     = "automod.manage"
+	PermissionPostApprove       = "post.approve"
+)
+
+const (
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+
+	moderatorLevel = 1
+	adminLevel     = 2
+)
+
+var ErrUnknownRole = errors.New("unknown role")
+
+var permissionDescriptions = map[string]string{
+	PermissionThreadDeleteAny:   "Delete threads of other users",
+	PermissionCommentDeleteAny:  "Delete comments of other users",
+	PermissionUserBan:           "Ban and unban users",
+	PermissionUserLookup:        "Look up user IDs by username",
+	PermissionModeratorAssign:   "Assign and remove moderators",
+	PermissionRoleAssign:        "Assign roles to users",
+	PermissionCategoryManage:    "Manage categories and their moderators",
+	PermissionSettingsManage:    "Change forum settings",
+	PermissionUserImpersonate:   "Impersonate users",
+	PermissionModerationLogView: "View the moderation log",
+	PermissionReportReview:      "Review and resolve reported content",
+	PermissionAutomodManage:     "Manage automatic moderation rules",
+	PermissionPostApprove:       "Approve threads and comments awaiting review",
+}
+
+// defaultRoles are created on startup and always hold at least these
+// permissions. Level orders roles, so staff can only act on users below them.
+var defaultRoles = []struct {
+	Name        string
+	Description string
+	Level       int
+	Permissions []string
+}{
+	{
+		Name:        RoleModerator,
+		Description: "Moderates threads, comments and users",
+		Level:       moderatorLevel,
+		Permissions: []string{
+			PermissionThreadDeleteAny,
+			PermissionCommentDeleteAny,
+			PermissionUserBan,
+			PermissionUserLookup,
+			PermissionModerationLogView,
+			PermissionReportReview,
+			PermissionPostApprove,
+		},
+	},
+	{
+		Name:        RoleAdmin,
+		Description: "Manages the forum, its staff and settings",
+		Level:       adminLevel,
+		Permissions: []string{
+			PermissionThreadDeleteAny,
+			PermissionCommentDeleteAny,
+			PermissionUserBan,
+			PermissionUserLookup,
+			PermissionModeratorAssign,
+			PermissionRoleAssign,
+			PermissionCategoryManage,
+			PermissionSettingsManage,
+			PermissionUserImpersonate,
+			PermissionModerationLogView,
+			PermissionReportReview,
+			PermissionAutomodManage,
+			PermissionPostApprove,
+		},
+	},
+}
+
+// SeedRoles creates the known permissions and default roles and grants the
+// default roles their permissions. It is safe to run on every startup.
+func SeedRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for name, description := range permissionDescriptions {
+			permission := models.Permission{Name: name, Description: description}
+			if err := tx.Where(models.Permission{Name: name}).FirstOrCreate(&permission).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, defaultRole := range defaultRoles {
+			role := models.Role{Name: defaultRole.Name, Description: defaultRole.Description, Level: defaultRole.Level}
+			if err := tx.Where(models.Role{Name: defaultRole.Name}).FirstOrCreate(&role).Error; err != nil {
+				return err
+			}
+
+			var permissions []models.Permission
+			if err := tx.Where("name IN ?", defaultRole.Permissions).Find(&permissions).Error; err != nil {
+				return err
+			}
+
+			for _, permission := range permissions {
+				grant := models.RolePermission{RoleID: role.RoleID, PermissionID: permission.PermissionID}
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&grant).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// MigrateRoleIDs gives users who were made moderators or admins through the
+// numeric users.role_id the matching role. It only runs while no user has a
+// role yet.
+func MigrateRoleIDs(db *gorm.DB) error {
+	var assigned int64
+	if err := db.Model(&models.UserRole{}).Count(&assigned).Error; err != nil || assigned > 0 {
+		return err
+	}
+
+	return db.Exec(`
+		INSERT INTO user_roles (user_id, role_id, created_at)
+		SELECT users.user_id, roles.role_id, NOW() FROM users
+		JOIN roles ON (users.role_id = 1 AND roles.name = ?) OR (users.role_id > 1 AND roles.name = ?)
+		ON CONFLICT DO NOTHING
+	`, RoleModerator, RoleAdmin).Error
+}
+
+// RBACManager resolves the permissions users get through their roles.
+// users.role_id is kept as the highest level among a user's roles so clients
+// can still tell staff apart, but access is decided by permissions and by the
+// levels of the roles in user_roles.
+type RBACManager struct {
+	db *gorm.DB
+}
+
+func NewRBACManager(db *gorm.DB) *RBACManager {
+	return &RBACManager{db: db}
+}
+
+func (m *RBACManager) Permissions(userID uint) ([]string, error) {
+	var permissions []string
+	err := m.db.Model(&models.Permission{}).
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.permission_id").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Order("permissions.name").
+		Pluck("permissions.name", &permissions).Error
+	return permissions, err
+}
+
+func (m *RBACManager) HasPermission(userID uint, permission string) (bool, error) {
+	var count int64
+	err := m.db.Model(&models.UserRole{}).
+		Joins("JOIN role_permissions ON role_permissions.role_id = user_roles.role_id").
+		Joins("JOIN permissions ON permissions.permission_id = role_permissions.permission_id").
+		Where("user_roles.user_id = ? AND permissions.name = ?", userID, permission).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (m *RBACManager) Roles(userID uint) ([]models.Role, error) {
+	var roles []models.Role
+	err := m.db.Joins("JOIN user_roles ON user_roles.role_id = roles.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.level DESC").
+		Find(&roles).Error
+	return roles, err
+}
+
+func (m *RBACManager) AllRoles() ([]models.Role, error) {
+	var roles []models.Role
+	err := m.db.Order("level DESC, name").Find(&roles).Error
+	return roles, err
+}
+
+// RolePermissions maps every role name to the permissions it grants.
+func (m *RBACManager) RolePermissions() (map[string][]string, error) {
+	var rows []struct {
+		RoleName       string
+		PermissionName string
+	}
+	if err := m.db.Model(&models.RolePermission{}).
+		Select("roles.name AS role_name, permissions.name AS permission_name").
+		Joins("JOIN roles ON roles.role_id = role_permissions.role_id").
+		Joins("JOIN permissions ON permissions.permission_id = role_permissions.permission_id").
+		Order("permissions.name").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	rolePermissions := map[string][]string{}
+	for _, row := range rows {
+		rolePermissions[row.RoleName] = append(rolePermissions[row.RoleName], row.PermissionName)
+	}
+	return rolePermissions, nil
+}
+
+// SetRoles replaces the roles of a user and updates their role level.
+func (m *RBACManager) SetRoles(user *models.User, roleNames []string) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		var roles []models.Role
+		if len(roleNames) > 0 {
+			if err := tx.Where("name IN ?", roleNames).Find(&roles).Error; err != nil {
+				return err
+			}
+			if len(roles) != len(uniqueStrings(roleNames)) {
+				return ErrUnknownRole
+			}
+		}
+
+		if err := tx.Where("user_id = ?", user.UserID).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+
+		for _, role := range roles {
+			if err := tx.Create(&models.UserRole{UserID: user.UserID, RoleID: role.RoleID}).Error; err != nil {
+				return err
+			}
+		}
+
+		return syncRoleLevel(tx, user)
+	})
+}
+
+// syncRoleLevel stores the highest level among the roles of a user in
+// users.role_id for clients. Access is never decided by it.
+func syncRoleLevel(tx *gorm.DB, user *models.User) error {
+	level, err := NewRBACManager(tx).RoleLevel(user.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.User{}).Where("user_id = ?", user.UserID).Update("role_id", level).Error; err != nil {
+		return err
+	}
+
+	user.RoleID = level
+	return nil
+}
+
+// AddRole and RemoveRole change a single role and keep the others.
+func (m *RBACManager) AddRole(user *models.User, roleName string) error {
+	names, err := m.RoleNames(user.UserID)
+	if err != nil {
+		return err
+	}
+	return m.SetRoles(user, append(names, roleName))
+}
+
+func (m *RBACManager) RemoveRole(user *models.User, roleName string) error {
+	names, err := m.RoleNames(user.UserID)
+	if err != nil {
+		return err
+	}
+
+	remaining := []string{}
+	for _, name := range names {
+		if name != roleName {
+			remaining = append(remaining, name)
+		}
+	}
+	return m.SetRoles(user, remaining)
+}
+
+func (m *RBACManager) RoleNames(userID uint) ([]string, error) {
+	roles, err := m.Roles(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = role.Name
+	}
+	return names, nil
+}
+
+// RoleLevel returns the highest level among the roles of a user, or zero for
+// users without one.
+func (m *RBACManager) RoleLevel(userID uint) (int, error) {
+	var level int
+	err := m.db.Model(&models.Role{}).
+		Select("COALESCE(MAX(roles.level), 0)").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Scan(&level).Error
+	return level, err
+}
+
+// IsStaff reports whether the user holds any role with a level, such as
+// moderator or admin. Moderating only some categories does not count.
+func (m *RBACManager) IsStaff(user *models.User) (bool, error) {
+	level, err := m.RoleLevel(user.UserID)
+	return level > 0, err
+}
+
+// Outranks reports whether the actor's highest role is above the target's,
+// which staff need to act on another user.
+func (m *RBACManager) Outranks(actor, target *models.User) (bool, error) {
+	actorLevel, err := m.RoleLevel(actor.UserID)
+	if err != nil {
+		return false, err
+	}
+
+	targetLevel, err := m.RoleLevel(target.UserID)
+	if err != nil {
+		return false, err
+	}
+	return actorLevel > targetLevel, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
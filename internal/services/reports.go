@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	ReportTargetThread  = "thread"
+	ReportTargetComment = "comment"
+
+	ReportStatusOpen     = "open"
+	ReportStatusResolved = "resolved"
+
+	ReportDismissed       = "dismissed"
+	ReportContentDeleted  = "content_deleted"
+	ReportAuthorSuspended = "author_suspended"
+
+	// Reports filed by automod have no reporter and this reason.
+	AutomodReporterID   uint = 0
+	ReportReasonAutomod      = "automod"
+)
+
+var ReportReasons = []string{"spam", "harassment", "inappropriate", "off_topic", "misinformation", "other"}
+
+var (
+	ErrReportTargetNotFound = errors.New("reported content not found")
+	ErrReportOwnContent     = errors.New("cannot report own content")
+	ErrDuplicateReport      = errors.New("content already reported")
+)
+
+// ReportGroup gathers the open reports about one thread or comment.
+type ReportGroup struct {
+	TargetType      string          `json:"target_type"`
+	TargetID        uint            `json:"target_id"`
+	TargetUserID    uint            `json:"target_user_id"`
+	CategoryID      uint            `json:"category_id"`
+	ReportCount     int64           `json:"report_count"`
+	FirstReportedAt time.Time       `json:"first_reported_at"`
+	LastReportedAt  time.Time       `json:"last_reported_at"`
+	Reasons         map[string]int  `gorm:"-" json:"reasons"`
+	Reports         []models.Report `gorm:"-" json:"reports"`
+	Content         interface{}     `gorm:"-" json:"content"`
+}
+
+type ReportManager struct {
+	db *gorm.DB
+}
+
+func NewReportManager(db *gorm.DB) *ReportManager {
+	return &ReportManager{db: db}
+}
+
+// Target looks up the author and category of a thread or comment that has not
+// been removed and that the reporter can see, so reports cannot be used to
+// probe for other users' held or pending posts.
+func (m *ReportManager) Target(reporter *models.User, targetType string, targetID uint) (uint, uint, error) {
+	switch targetType {
+	case ReportTargetThread:
+		var thread models.Thread
+		if err := m.db.Scopes(VisibleTo(reporter)).Where("is_deleted = ?", false).First(&thread, targetID).Error; err != nil {
+			return 0, 0, ErrReportTargetNotFound
+		}
+		return thread.UserID, thread.CategoryID, nil
+	case ReportTargetComment:
+		var comment models.Comment
+		if err := m.db.Scopes(VisibleTo(reporter)).Where("is_deleted = ?", false).First(&comment, targetID).Error; err != nil {
+			return 0, 0, ErrReportTargetNotFound
+		}
+
+		var thread models.Thread
+		if err := m.db.Scopes(VisibleTo(reporter)).First(&thread, comment.ThreadID).Error; err != nil {
+			return 0, 0, ErrReportTargetNotFound
+		}
+		return comment.UserID, thread.CategoryID, nil
+	}
+
+	return 0, 0, ErrReportTargetNotFound
+}
+
+// Create files a report. Users cannot report their own content or report the
+// same content again while their earlier report is open.
+func (m *ReportManager) Create(reporter *models.User, targetType string, targetID uint, reason, note string) (*models.Report, error) {
+	authorID, categoryID, err := m.Target(reporter, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if authorID == reporter.UserID {
+		return nil, ErrReportOwnContent
+	}
+
+	var open int64
+	if err := m.db.Model(&models.Report{}).
+		Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status = ?", reporter.UserID, targetType, targetID, ReportStatusOpen).
+		Count(&open).Error; err != nil {
+		return nil, err
+	}
+	if open > 0 {
+		return nil, ErrDuplicateReport
+	}
+
+	report := models.Report{
+		ReporterID:   reporter.UserID,
+		TargetType:   targetType,
+		TargetID:     targetID,
+		TargetUserID: authorID,
+		CategoryID:   categoryID,
+		Reason:       reason,
+		Note:         note,
+		Status:       ReportStatusOpen,
+	}
+	if err := m.db.Create(&report).Error; err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// OpenReports lists the open reports about one thread or comment.
+func (m *ReportManager) OpenReports(targetType string, targetID uint) ([]models.Report, error) {
+	var reports []models.Report
+	err := m.db.Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, ReportStatusOpen).
+		Order("created_at").
+		Find(&reports).Error
+	return reports, err
+}
+
+// Queue groups open reports by their target, most reported first. A nil
+// categoryIDs includes every category.
+func (m *ReportManager) Queue(categoryIDs []uint, targetType string, limit, offset int) ([]ReportGroup, int64, error) {
+	query := m.db.Model(&models.Report{}).Where("status = ?", ReportStatusOpen)
+	if categoryIDs != nil {
+		query = query.Where("category_id IN ?", categoryIDs)
+	}
+	if targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	query = query.Group("target_type, target_id, target_user_id, category_id").Session(&gorm.Session{})
+
+	var total int64
+	if err := m.db.Table("(?) AS report_groups", query.Select("target_type, target_id")).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	groups := []ReportGroup{}
+	if err := query.
+		Select("target_type, target_id, target_user_id, category_id, COUNT(*) AS report_count, MIN(created_at) AS first_reported_at, MAX(created_at) AS last_reported_at").
+		Order("report_count DESC, first_reported_at").
+		Limit(limit).
+		Offset(offset).
+		Scan(&groups).Error; err != nil {
+		return nil, 0, err
+	}
+
+	for i := range groups {
+		group := &groups[i]
+
+		reports, err := m.OpenReports(group.TargetType, group.TargetID)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		group.Reports = reports
+		group.Reasons = map[string]int{}
+		for _, report := range reports {
+			group.Reasons[report.Reason]++
+		}
+
+		if group.TargetType == ReportTargetThread {
+			var thread models.Thread
+			if err := m.db.First(&thread, group.TargetID).Error; err == nil {
+				group.Content = thread
+			}
+		} else {
+			var comment models.Comment
+			if err := m.db.First(&comment, group.TargetID).Error; err == nil {
+				group.Content = comment
+			}
+		}
+	}
+
+	return groups, total, nil
+}
+
+// Resolve closes every open report about the target with the same resolution
+// and returns them.
+func (m *ReportManager) Resolve(targetType string, targetID uint, resolution, note string, moderator *models.User) ([]models.Report, error) {
+	var reports []models.Report
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, ReportStatusOpen).
+			Find(&reports).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for i := range reports {
+			reports[i].Status = ReportStatusResolved
+			reports[i].Resolution = resolution
+			reports[i].ResolutionNote = note
+			reports[i].ResolvedBy = &moderator.UserID
+			reports[i].ResolvedAt = &now
+			if err := tx.Save(&reports[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return reports, err
+}
+
+// SendReportResolvedEmail tells a reporter that their report was reviewed and
+// what came of it, without naming the moderator.
+func SendReportResolvedEmail(ctx context.Context, mailer Mailer, reporter *models.User, email string, report *models.Report) error {
+	outcome := "A moderator reviewed it and found that it does not break the forum rules."
+	switch report.Resolution {
+	case ReportContentDeleted:
+		outcome = fmt.Sprintf("A moderator reviewed it and removed the %s.", report.TargetType)
+	case ReportAuthorSuspended:
+		outcome = fmt.Sprintf("A moderator reviewed it and suspended the author of the %s.", report.TargetType)
+	}
+
+	return mailer.Send(ctx, Mail{
+		To:      email,
+		Subject: "Your Olympliance report was reviewed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nThank you for reporting a %s on Olympliance. %s\n\nYou can see all of your reports and their outcomes in your account.\n",
+			reporter.Username, report.TargetType, outcome,
+		),
+	})
+}
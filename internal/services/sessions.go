@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrSessionRevoked      = errors.New("session revoked")
+)
+
+// SessionManager keeps track of the server-side sessions behind refresh
+// tokens. Every refresh rotates the token and presenting a token that has
+// already been rotated revokes the whole session.
+type SessionManager struct {
+	db *gorm.DB
+}
+
+func NewSessionManager(db *gorm.DB) *SessionManager {
+	return &SessionManager{db: db}
+}
+
+// StartSession creates a new session for the user and sets both token cookies.
+func (m *SessionManager) StartSession(c *gin.Context, user *models.User) (*models.Session, error) {
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.UserID,
+		UserAgent:  c.Request.UserAgent(),
+		IPAddress:  c.ClientIP(),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(RefreshTokenDuration),
+	}
+
+	tokenID, err := RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	session.RefreshTokenHash = HashToken(tokenID)
+
+	if err := m.db.Create(&session).Error; err != nil {
+		return nil, err
+	}
+
+	if err := m.issueTokens(c, &session, tokenID); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// RefreshSession validates the refresh token cookie, rotates it and issues a
+// new access token. It returns the owner of the session.
+func (m *SessionManager) RefreshSession(c *gin.Context) (*models.User, *models.Session, error) {
+	refreshToken, err := c.Cookie("refresh_token")
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	claims, err := ParseToken(refreshToken, RefreshTokenType)
+	if err != nil || claims.ID == "" {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	var session models.Session
+	if err := m.db.First(&session, claims.SessionID).Error; err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	if session.UserID != claims.UserID {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	if session.RevokedAt != nil || session.ExpiresAt.Before(time.Now()) {
+		return nil, nil, ErrSessionRevoked
+	}
+
+	if session.RefreshTokenHash != HashToken(claims.ID) {
+		if err := m.RevokeSession(session.SessionID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	var user models.User
+	if err := m.db.First(&user, session.UserID).Error; err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	newTokenID, err := RandomToken(32)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	result := m.db.Model(&models.Session{}).
+		Where("session_id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", session.SessionID, session.RefreshTokenHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": HashToken(newTokenID),
+			"last_used_at":       now,
+			"expires_at":         now.Add(RefreshTokenDuration),
+			"ip_address":         c.ClientIP(),
+		})
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	// Another request rotated the same token first, so this one is a replay.
+	if result.RowsAffected == 0 {
+		if err := m.RevokeSession(session.SessionID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(RefreshTokenDuration)
+	if err := m.issueTokens(c, &session, newTokenID); err != nil {
+		return nil, nil, err
+	}
+
+	return &user, &session, nil
+}
+
+// ValidateSession makes sure the session behind an access token is still
+// active and records when it was last used.
+func (m *SessionManager) ValidateSession(sessionID, userID uint) error {
+	var session models.Session
+	if err := m.db.First(&session, sessionID).Error; err != nil {
+		return ErrSessionRevoked
+	}
+
+	if session.UserID != userID || session.RevokedAt != nil || session.ExpiresAt.Before(time.Now()) {
+		return ErrSessionRevoked
+	}
+
+	// Avoid writing on every request, a minute of precision is plenty.
+	if time.Since(session.LastUsedAt) > time.Minute {
+		if err := m.db.Model(&session).Update("last_used_at", time.Now()).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *SessionManager) ActiveSessions(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := m.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (m *SessionManager) RevokeSession(sessionID uint) error {
+	return m.db.Model(&models.Session{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeUserSessions revokes every active session of the user except the one
+// with exceptSessionID. Pass 0 to revoke all of them.
+func (m *SessionManager) RevokeUserSessions(userID uint, exceptSessionID uint) error {
+	return m.db.Model(&models.Session{}).
+		Where("user_id = ? AND session_id <> ? AND revoked_at IS NULL", userID, exceptSessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (m *SessionManager) issueTokens(c *gin.Context, session *models.Session, refreshTokenID string) error {
+	now := time.Now()
+
+	accessToken, err := SignToken(Claims{
+		UserID:    session.UserID,
+		SessionID: session.SessionID,
+		TokenType: AccessTokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenDuration)),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	refreshToken, err := SignToken(Claims{
+		UserID:    session.UserID,
+		SessionID: session.SessionID,
+		TokenType: RefreshTokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        refreshTokenID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(RefreshTokenDuration)),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	SetCookie(c, "refresh_token", refreshToken, int(RefreshTokenDuration.Seconds()))
+	SetCookie(c, "access_token", accessToken, int(AccessTokenDuration.Seconds()))
+	return nil
+}
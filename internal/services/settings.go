@@ -0,0 +1,31 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const SettingRequireModeratorTwoFactor = "require_moderator_2fa"
+
+// GetSetting returns the stored value of an admin setting, or fallback when
+// it was never set.
+func GetSetting(db *gorm.DB, key, fallback string) (string, error) {
+	var setting models.Setting
+	if err := db.First(&setting, "key = ?", key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fallback, nil
+		}
+		return "", err
+	}
+	return setting.Value, nil
+}
+
+func SetSetting(db *gorm.DB, key, value string) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&models.Setting{Key: key, Value: value}).Error
+}
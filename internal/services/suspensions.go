@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrNotSuspended = errors.New("user is not suspended")
+
+// SuspensionManager keeps users.is_banned in step with the suspensions of a
+// user. A suspension without an end date lasts until it is lifted.
+type SuspensionManager struct {
+	db *gorm.DB
+}
+
+func NewSuspensionManager(db *gorm.DB) *SuspensionManager {
+	return &SuspensionManager{db: db}
+}
+
+func activeSuspensions(db *gorm.DB, userID uint, now time.Time) *gorm.DB {
+	return db.Model(&models.Suspension{}).
+		Where("user_id = ? AND lifted_at IS NULL AND starts_at <= ?", userID, now).
+		Where("ends_at IS NULL OR ends_at > ?", now)
+}
+
+// Active returns the suspension that keeps the user out the longest, or nil.
+// Users whose suspensions have all ended are unbanned on the way.
+func (m *SuspensionManager) Active(user *models.User) (*models.Suspension, error) {
+	if !user.IsBanned {
+		return nil, nil
+	}
+
+	var suspension models.Suspension
+	err := activeSuspensions(m.db, user.UserID, time.Now()).
+		Order("ends_at DESC NULLS FIRST").
+		First(&suspension).Error
+	if err == nil {
+		return &suspension, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if err := m.db.Model(&models.User{}).Where("user_id = ?", user.UserID).Update("is_banned", false).Error; err != nil {
+		return nil, err
+	}
+
+	user.IsBanned = false
+	return nil, nil
+}
+
+// Suspend starts a suspension now and signs the user out everywhere. A zero
+// duration suspends the user until a moderator lifts it and also removes
+// their threads and comments.
+func (m *SuspensionManager) Suspend(user, moderator *models.User, reason string, duration time.Duration) (*models.Suspension, error) {
+	now := time.Now()
+	suspension := models.Suspension{
+		UserID:      user.UserID,
+		ModeratorID: &moderator.UserID,
+		Reason:      reason,
+		StartsAt:    now,
+	}
+	if duration > 0 {
+		endsAt := now.Add(duration)
+		suspension.EndsAt = &endsAt
+	}
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&suspension).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.User{}).Where("user_id = ?", user.UserID).Update("is_banned", true).Error; err != nil {
+			return err
+		}
+
+		if err := NewSessionManager(tx).RevokeUserSessions(user.UserID, 0); err != nil {
+			return err
+		}
+
+		if suspension.EndsAt != nil {
+			return nil
+		}
+
+		removal := RemovalColumns(RemovedBySuspension, moderator.UserID, &suspension.SuspensionID)
+		for _, model := range []interface{}{&models.Thread{}, &models.Comment{}} {
+			if err := tx.Model(model).Where("user_id = ? AND is_deleted = ?", user.UserID, false).Updates(removal).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.IsBanned = true
+	return &suspension, nil
+}
+
+// Lift ends every active suspension of the user early and restores the
+// content that was removed with them. It returns how many threads and
+// comments were restored.
+func (m *SuspensionManager) Lift(user, moderator *models.User) (int64, error) {
+	now := time.Now()
+	var restored int64
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		result := activeSuspensions(tx, user.UserID, now).
+			Updates(map[string]interface{}{"lifted_at": now, "lifted_by": moderator.UserID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 && !user.IsBanned {
+			return ErrNotSuspended
+		}
+
+		if err := tx.Model(&models.User{}).Where("user_id = ?", user.UserID).Update("is_banned", false).Error; err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{&models.Thread{}, &models.Comment{}} {
+			result := suspendedContent(tx, model, user.UserID).Updates(restoreColumns())
+			if result.Error != nil {
+				return result.Error
+			}
+			restored += result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	user.IsBanned = false
+	return restored, nil
+}
+
+// RestorableContent lists the threads and comments that lifting the
+// suspensions of the user would restore.
+func (m *SuspensionManager) RestorableContent(userID uint) (*RemovedContent, error) {
+	content := RemovedContent{Threads: []models.Thread{}, Comments: []models.Comment{}}
+	if err := suspendedContent(m.db, &models.Thread{}, userID).Order("created_at").Find(&content.Threads).Error; err != nil {
+		return nil, err
+	}
+	if err := suspendedContent(m.db, &models.Comment{}, userID).Order("created_at").Find(&content.Comments).Error; err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+func (m *SuspensionManager) History(userID uint) ([]models.Suspension, error) {
+	suspensions := []models.Suspension{}
+	err := m.db.Where("user_id = ?", userID).Order("starts_at DESC").Find(&suspensions).Error
+	return suspensions, err
+}
+
+// LiftExpired unbans every user whose suspensions have all ended.
+func (m *SuspensionManager) LiftExpired() error {
+	now := time.Now()
+	return m.db.Model(&models.User{}).
+		Where("is_banned").
+		Where(`NOT EXISTS (
+			SELECT 1 FROM suspensions WHERE suspensions.user_id = users.user_id
+			AND suspensions.lifted_at IS NULL AND suspensions.starts_at <= ?
+			AND (suspensions.ends_at IS NULL OR suspensions.ends_at > ?)
+		)`, now, now).
+		Update("is_banned", false).Error
+}
+
+func (m *SuspensionManager) LiftExpiredEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := m.LiftExpired(); err != nil {
+			log.Printf("Error lifting expired suspensions: %v", err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AccessTokenType  = "access"
+	RefreshTokenType = "refresh"
+
+	AccessTokenDuration  = 15 * time.Minute
+	RefreshTokenDuration = 7 * 24 * time.Hour
+)
+
+type Claims struct {
+	UserID    uint
+	SessionID uint
+	TokenType string
+	jwt.RegisteredClaims
+}
+
+// SignClaims signs any claims with the active key of the default key ring.
+func SignClaims(claims jwt.Claims) (string, error) {
+	ring, err := DefaultKeyRing()
+	if err != nil {
+		return "", err
+	}
+	return ring.Sign(claims)
+}
+
+// ParseClaims verifies a token signed by any key the key ring still accepts
+// and decodes it into claims.
+func ParseClaims(tokenString string, claims jwt.Claims) error {
+	ring, err := DefaultKeyRing()
+	if err != nil {
+		return err
+	}
+
+	token, err := ring.Parse(tokenString, claims)
+	if err != nil {
+		return err
+	}
+
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+
+	return nil
+}
+
+func SignToken(claims Claims) (string, error) {
+	return SignClaims(claims)
+}
+
+// ParseToken validates the signature and expiry of a token and makes sure it
+// is of the expected type, so a refresh token cannot be used as an access
+// token and vice versa.
+func ParseToken(tokenString, tokenType string) (*Claims, error) {
+	claims := &Claims{}
+	if err := ParseClaims(tokenString, claims); err != nil {
+		return nil, err
+	}
+
+	if claims.TokenType != tokenType {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	TwoFactorTokenType = "two_factor"
+
+	twoFactorChallengeCookie   = "two_factor_challenge"
+	twoFactorChallengeDuration = 5 * time.Minute
+	recoveryCodeCount          = 10
+)
+
+var (
+	ErrInvalidTwoFactorCode      = errors.New("invalid two-factor code")
+	ErrInvalidTwoFactorChallenge = errors.New("invalid two-factor challenge")
+)
+
+type TwoFactorManager struct {
+	db *gorm.DB
+}
+
+func NewTwoFactorManager(db *gorm.DB) *TwoFactorManager {
+	return &TwoFactorManager{db: db}
+}
+
+func (m *TwoFactorManager) IsEnabled(userID uint) (bool, error) {
+	var count int64
+	err := m.db.Model(&models.TwoFactor{}).
+		Where("user_id = ? AND is_enabled = ?", userID, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// IsRequired reports whether an admin has made two-factor authentication
+// mandatory for the user's role.
+func (m *TwoFactorManager) IsRequired(user *models.User) (bool, error) {
+	isStaff, err := NewRBACManager(m.db).IsStaff(user)
+	if err != nil || !isStaff {
+		return false, err
+	}
+
+	value, err := GetSetting(m.db, SettingRequireModeratorTwoFactor, "false")
+	if err != nil {
+		return false, err
+	}
+	return value == "true", nil
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code.
+func (m *TwoFactorManager) Verify(userID uint, code string) error {
+	var twoFactor models.TwoFactor
+	if err := m.db.Where("user_id = ? AND is_enabled = ?", userID, true).First(&twoFactor).Error; err != nil {
+		return ErrInvalidTwoFactorCode
+	}
+
+	if step, ok := ValidateTOTP(twoFactor.Secret, code, time.Now(), twoFactor.LastUsedStep); ok {
+		return m.markStepUsed(userID, twoFactor.LastUsedStep, step)
+	}
+
+	return m.useRecoveryCode(userID, code)
+}
+
+// VerifyPending checks the first code from a freshly enrolled authenticator
+// and turns two-factor authentication on.
+func (m *TwoFactorManager) VerifyPending(userID uint, code string) error {
+	var twoFactor models.TwoFactor
+	if err := m.db.Where("user_id = ? AND is_enabled = ?", userID, false).First(&twoFactor).Error; err != nil {
+		return ErrInvalidTwoFactorCode
+	}
+
+	step, ok := ValidateTOTP(twoFactor.Secret, code, time.Now(), twoFactor.LastUsedStep)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	now := time.Now()
+	return m.db.Model(&twoFactor).Updates(map[string]interface{}{
+		"is_enabled":     true,
+		"enabled_at":     now,
+		"last_used_step": step,
+	}).Error
+}
+
+func (m *TwoFactorManager) Disable(userID uint) error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.TwoFactor{}).Error
+	})
+}
+
+// GenerateRecoveryCodes replaces the user's recovery codes and returns the new
+// ones in plain text. Only their digests are stored.
+func (m *TwoFactorManager) GenerateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := RandomToken(10)
+		if err != nil {
+			return nil, err
+		}
+		code := raw[0:5] + "-" + raw[5:10] + "-" + raw[10:15] + "-" + raw[15:20]
+		codes = append(codes, code)
+		records = append(records, models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: HashToken(normalizeRecoveryCode(code)),
+		})
+	}
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&records).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (m *TwoFactorManager) RemainingRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := m.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (m *TwoFactorManager) markStepUsed(userID uint, lastUsedStep, step int64) error {
+	result := m.db.Model(&models.TwoFactor{}).
+		Where("user_id = ? AND last_used_step = ?", userID, lastUsedStep).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	// A concurrent request already used a code, so this one may be a replay.
+	if result.RowsAffected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func (m *TwoFactorManager) useRecoveryCode(userID uint, code string) error {
+	result := m.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, HashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// SetTwoFactorChallenge remembers, for a few minutes, which user passed the
+// first login step so the second step only has to send the code.
+func SetTwoFactorChallenge(c *gin.Context, userID uint) error {
+	now := time.Now()
+	challenge, err := SignToken(Claims{
+		UserID:    userID,
+		TokenType: TwoFactorTokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(twoFactorChallengeDuration)),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	SetCookie(c, twoFactorChallengeCookie, challenge, int(twoFactorChallengeDuration.Seconds()))
+	return nil
+}
+
+func TwoFactorChallengeUserID(c *gin.Context) (uint, error) {
+	challenge, err := c.Cookie(twoFactorChallengeCookie)
+	if err != nil {
+		return 0, ErrInvalidTwoFactorChallenge
+	}
+
+	claims, err := ParseToken(challenge, TwoFactorTokenType)
+	if err != nil {
+		return 0, ErrInvalidTwoFactorChallenge
+	}
+
+	return claims.UserID, nil
+}
+
+func ClearTwoFactorChallenge(c *gin.Context) {
+	SetCookie(c, twoFactorChallengeCookie, "", -1)
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/oadultradeepfield/olympliance-server/internal/models"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+const maxGeneratedUsernameLength = 24
+
+var (
+	validUsername      = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	invalidUsernameRun = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+func IsValidUsername(username string) bool {
+	return validUsername.MatchString(username)
+}
+
+// GenerateUsername turns a display name such as "Jane Doe" into a unique
+// username such as "jane_doe", adding a numeric suffix when it is taken.
+func GenerateUsername(db *gorm.DB, name string) (string, error) {
+	base := slugifyUsername(name)
+
+	candidate := base
+	for attempt := 0; attempt < 10; attempt++ {
+		var count int64
+		if err := db.Model(&models.User{}).Where("LOWER(username) = LOWER(?)", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s_%d", base, 1000+rand.IntN(9000))
+	}
+
+	return "", errors.New("failed to find an available username")
+}
+
+func slugifyUsername(name string) string {
+	var builder strings.Builder
+	for _, r := range norm.NFKD.String(strings.ToLower(name)) {
+		// Drop the accents left over from decomposing letters like "é".
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		builder.WriteRune(r)
+	}
+
+	slug := strings.Trim(invalidUsernameRun.ReplaceAllString(builder.String(), "_"), "_")
+	if len(slug) > maxGeneratedUsernameLength {
+		slug = strings.TrimRight(slug[:maxGeneratedUsernameLength], "_")
+	}
+	if slug == "" {
+		slug = "user"
+	}
+
+	return slug
+}
@@ -1,5 +1,11 @@
 package services
 
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
 func Contains(slice []string, item string) bool {
 	for _, v := range slice {
 		if v == item {
@@ -8,3 +14,19 @@ func Contains(slice []string, item string) bool {
 	}
 	return false
 }
+
+// RandomToken returns a hex encoded string built from n random bytes.
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the hex encoded SHA-256 digest of a token so that only
+// the digest needs to be stored in the database.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}